@@ -0,0 +1,303 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+
+	"github.com/HuXin0817/dots-and-boxes/engine"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+)
+
+// The HTTP API lets scripts and dashboards drive and watch the game. GET /api/game returns the state of the game
+// with its moves and GET /api/moves the moves alone. POST /api/move with {"move": "a1-b1"}, POST /api/undo,
+// POST /api/restart with an optional {"boardSize": 5} and POST /api/ai/1 or /api/ai/2 with an optional {"on": true}
+// run the commands of the control protocol and return the new state, or an error with status 400.
+// GET /api/events is a WebSocket streaming an event for every move, undo and restart. When the API listens on the
+// network, the commands are only run for clients on this machine, and the moves also for browsers holding a seat of
+// the player to move, see requireHost.
+
+const (
+	DefaultAPIAddress = "localhost:6061" // Default address of the HTTP API
+	APIEventBuffer    = 64               // Events kept for a slow WebSocket client before it is dropped
+)
+
+// APIState is the state of the game returned by the HTTP API.
+type APIState struct {
+	BoardSize    int                 `json:"boardSize"`         // Number of dots on each side of the board
+	Position     string              `json:"position"`          // Notation of the position
+	Player1Score int                 `json:"player1Score"`      // Score of Player 1
+	Player2Score int                 `json:"player2Score"`      // Score of Player 2
+	Turn         engine.Turn         `json:"turn"`              // Player to move
+	Step         int                 `json:"step"`              // Number of moves played
+	Over         bool                `json:"over"`              // Whether the game is over
+	Result       string              `json:"result,omitempty"`  // Result of the game once it is over
+	AIPlayer1    bool                `json:"aiPlayer1"`         // Whether Player 1 is played by the AI
+	AIPlayer2    bool                `json:"aiPlayer2"`         // Whether Player 2 is played by the AI
+	Player1Seat  bool                `json:"player1Seat"`       // Whether Player 1 is played in a browser
+	Player2Seat  bool                `json:"player2Seat"`       // Whether Player 2 is played in a browser
+	Records      []engine.MoveRecord `json:"records,omitempty"` // Moves of the game, left out of the events
+}
+
+// APIEvent is an event of the game streamed by the WebSocket of the HTTP API.
+type APIEvent struct {
+	Type   string             `json:"type"`             // move, undo or restart, or state for the first event
+	Record *engine.MoveRecord `json:"record,omitempty"` // Move drawn or undone
+	State  APIState           `json:"state"`            // State of the game after the event
+}
+
+// apiServer is the HTTP API of the game with the WebSocket clients it streams events to.
+type apiServer struct {
+	server  *http.Server           // HTTP server of the API
+	lock    sync.Mutex             // Mutex for clients synchronization
+	clients map[chan APIEvent]bool // Event queue of each WebSocket client
+	seats   map[engine.Turn]string // Token of the browser playing each seated player, guarded by globalLock
+}
+
+// API is the running HTTP API, nil when it is off. It is guarded by globalLock.
+var API *apiServer
+
+// newAPIState returns the state of game g. It must be called with globalLock held.
+func newAPIState(g *engine.Game, withRecords bool) APIState {
+	s := APIState{
+		BoardSize:    g.BoardSize,
+		Position:     g.Position().String(),
+		Player1Score: g.Player1Score(),
+		Player2Score: g.Player2Score(),
+		Turn:         g.Turn(),
+		Step:         g.Step(),
+		Over:         g.Over(),
+		AIPlayer1:    Chess.AIPlayer1,
+		AIPlayer2:    Chess.AIPlayer2,
+		Player1Seat:  API.Seated(engine.Player1Turn),
+		Player2Seat:  API.Seated(engine.Player2Turn),
+	}
+	if s.Over {
+		s.Result = winMessage(g)
+	}
+	if withRecords {
+		s.Records = g.Records()
+	}
+	return s
+}
+
+// StartAPI starts serving the HTTP API on the address. It must be called with globalLock held.
+func StartAPI(address string) {
+	a := &apiServer{clients: make(map[chan APIEvent]bool), seats: make(map[engine.Turn]string)}
+	r := gin.New()
+	r.Use(gin.Recovery(), func(c *gin.Context) {
+		if err := checkSameOrigin(c.Request); err != nil {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		}
+	})
+	r.GET("/api/game", func(c *gin.Context) {
+		globalLock.Lock()
+		defer globalLock.Unlock()
+		c.JSON(http.StatusOK, newAPIState(game.Game(), true))
+	})
+	r.GET("/api/moves", func(c *gin.Context) {
+		globalLock.Lock()
+		defer globalLock.Unlock()
+		c.JSON(http.StatusOK, game.Game().Records())
+	})
+	r.POST("/api/move", a.requireSeatOrHost, func(c *gin.Context) {
+		var body struct {
+			Move string `json:"move"`
+		}
+		if err := c.ShouldBindJSON(&body); err != nil {
+			apiReply(c, err)
+			return
+		}
+		if token := c.GetHeader(SeatTokenHeader); token != "" {
+			apiReply(c, a.seatMove(token, body.Move))
+			return
+		}
+		_, err := controlMove([]string{body.Move})
+		apiReply(c, err)
+	})
+	r.POST("/api/undo", a.requireHost, func(c *gin.Context) {
+		_, err := controlUndo(nil)
+		apiReply(c, err)
+	})
+	r.POST("/api/restart", a.requireHost, func(c *gin.Context) {
+		var body struct {
+			BoardSize int `json:"boardSize"`
+		}
+		var args []string
+		if c.Request.ContentLength != 0 {
+			if err := c.ShouldBindJSON(&body); err != nil {
+				apiReply(c, err)
+				return
+			}
+		}
+		if body.BoardSize != 0 {
+			args = append(args, strconv.Itoa(body.BoardSize))
+		}
+		_, err := controlRestart(args)
+		apiReply(c, err)
+	})
+	r.POST("/api/ai/:player", a.requireHost, func(c *gin.Context) {
+		var body struct {
+			On *bool `json:"on"`
+		}
+		if c.Request.ContentLength != 0 {
+			if err := c.ShouldBindJSON(&body); err != nil {
+				apiReply(c, err)
+				return
+			}
+		}
+		args := []string{c.Param("player")}
+		if body.On != nil {
+			args = append(args, map[bool]string{true: "on", false: "off"}[*body.On])
+		}
+		_, err := controlAI(args)
+		apiReply(c, err)
+	})
+	a.registerWeb(r)
+	r.GET("/api/events", gin.WrapH(websocket.Server{Handler: a.stream, Handshake: func(config *websocket.Config, req *http.Request) error {
+		config.Origin, _ = url.Parse(req.Header.Get("Origin")) // Checked by checkSameOrigin
+		return nil
+	}}))
+
+	a.server = &http.Server{Addr: address, Handler: r}
+	API = a
+	Message.Send("HTTP API on http://%v/api/game", address)
+	go func() {
+		if err := a.server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			Message.Send(err.Error())
+			globalLock.Lock()
+			defer globalLock.Unlock()
+			if API == a {
+				StopAPI()
+				Chess.HTTPAPI = false
+				RefreshMenu()
+			}
+		}
+	}()
+}
+
+// StopAPI stops the HTTP API and disconnects its WebSocket clients. It must be called with globalLock held.
+func StopAPI() {
+	if API == nil {
+		return
+	}
+	if err := API.server.Close(); err != nil {
+		Message.Send(err.Error())
+	}
+	API.lock.Lock()
+	for events := range API.clients {
+		delete(API.clients, events)
+		close(events)
+	}
+	API.lock.Unlock()
+	API = nil
+	Scheduler.Notify(ToggleEvent) // The AI players take the seats of the browsers back
+}
+
+// apiReply answers a command of the HTTP API with the new state of the game, or with the error of the command.
+func apiReply(c *gin.Context, err error) {
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	globalLock.Lock()
+	defer globalLock.Unlock()
+	c.JSON(http.StatusOK, newAPIState(game.Game(), true))
+}
+
+// requireHost lets the commands through for the clients on this machine only, so that neither the spectators on the
+// network nor the browsers holding a seat can undo, restart or toggle the AI players.
+func (a *apiServer) requireHost(c *gin.Context) {
+	if host, _, err := net.SplitHostPort(c.Request.RemoteAddr); err == nil {
+		if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+			return
+		}
+	}
+	c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "only this machine runs commands"})
+}
+
+// requireSeatOrHost lets the moves sent with a seat token through, seatMove checking that the token holds the seat of
+// the player to move, and the other moves through requireHost.
+func (a *apiServer) requireSeatOrHost(c *gin.Context) {
+	if c.GetHeader(SeatTokenHeader) == "" {
+		a.requireHost(c)
+	}
+}
+
+// checkSameOrigin accepts the clients that are not browsers, which send no origin, and the pages served from the
+// address of the API, so that other web sites can neither drive nor watch the game.
+func checkSameOrigin(req *http.Request) error {
+	origin := req.Header.Get("Origin")
+	if origin == "" {
+		return nil
+	}
+	if u, err := url.Parse(origin); err != nil || u.Host != req.Host {
+		return fmt.Errorf("origin %q not allowed", origin)
+	}
+	return nil
+}
+
+// Publish sends the event of the given type to every WebSocket client. A client too slow to keep up is dropped,
+// so that it never blocks the game. It must be called with globalLock held.
+func (a *apiServer) Publish(eventType string, record *engine.MoveRecord) {
+	if a == nil {
+		return
+	}
+	e := APIEvent{Type: eventType, Record: record, State: newAPIState(game.Game(), false)}
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	for events := range a.clients {
+		select {
+		case events <- e:
+		default:
+			delete(a.clients, events)
+			close(events)
+		}
+	}
+}
+
+// stream sends the state of the game to a WebSocket client, then the events of the game until either side leaves.
+// The seats taken with the token of the query, if any, are left when the client leaves.
+func (a *apiServer) stream(ws *websocket.Conn) {
+	defer ws.Close()
+	if token := ws.Request().URL.Query().Get("token"); token != "" {
+		defer func() {
+			globalLock.Lock()
+			defer globalLock.Unlock()
+			if API == a {
+				a.leaveSeats(token)
+				game.Refresh()
+			}
+		}()
+	}
+	events := make(chan APIEvent, APIEventBuffer)
+	globalLock.Lock()
+	events <- APIEvent{Type: "state", State: newAPIState(game.Game(), false)}
+	a.lock.Lock()
+	a.clients[events] = true
+	a.lock.Unlock()
+	globalLock.Unlock()
+
+	go func() {
+		// The client sends nothing, so a failed read means that it left
+		var discard []byte
+		for websocket.Message.Receive(ws, &discard) == nil {
+		}
+		a.lock.Lock()
+		defer a.lock.Unlock()
+		if a.clients[events] {
+			delete(a.clients, events)
+			close(events)
+		}
+	}()
+	for e := range events {
+		if err := websocket.JSON.Send(ws, e); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image/color"
+	"image/png"
+	"os"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/HuXin0817/dots-and-boxes/engine"
+)
+
+const (
+	ChartWidth       = 640          // Width of the plot of the chart window
+	ChartHeight      = 320          // Height of the plot of the chart window
+	ChartMargin      = 32           // Margin around the plot
+	ChartRollouts    = 2000         // Number of rollouts estimating the win probability of a position
+	ChartFileSuffix  = ".chart.png" // Suffix of the chart image saved next to the game log
+	chartPointRadius = float32(3.5) // Radius of the points of the chart
+)
+
+var (
+	ChartMarginColor = color.NRGBA{R: 0x40, G: 0x40, B: 0xFF, A: 0xFF} // #4040FFFF, score difference
+	ChartWinColor    = color.NRGBA{R: 0x40, G: 0xC0, B: 0x40, A: 0xFF} // #40C040FF, win probability
+)
+
+// chartPoint is the state of the game after a step.
+type chartPoint struct {
+	margin int     // Score of Player 1 minus the score of Player 2
+	win    float64 // Probability that Player 1 wins, estimated with rollouts until the endgame solver takes over
+}
+
+// chartColumn is the tappable area of the chart above a step.
+type chartColumn struct {
+	widget.BaseWidget
+	onTapped func() // Called when the column is tapped
+}
+
+// newChartColumn creates the tappable area of a step.
+func newChartColumn(onTapped func()) *chartColumn {
+	c := &chartColumn{onTapped: onTapped}
+	c.ExtendBaseWidget(c)
+	return c
+}
+
+// CreateRenderer returns the renderer of the column, which draws nothing.
+func (c *chartColumn) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(canvas.NewRectangle(color.Transparent))
+}
+
+// Tapped calls the tap handler of the column.
+func (c *chartColumn) Tapped(*fyne.PointEvent) { c.onTapped() }
+
+// ShowChart computes the score difference and the win probability after every move of the records in the background,
+// and opens a chart of them. The probability comes from ChartRollouts rollouts of the greedy policy, or from the
+// endgame solver below the solver threshold of the AI player, Player 2 when only Player 2 is played by the AI.
+// Tapping a step of the chart recovers the game at that step.
+func ShowChart(boardSize int, records []engine.MoveRecord) {
+	records = append([]engine.MoveRecord{}, records...)
+	player := engine.Player1Turn
+	if Chess.AIPlayer2 && !Chess.AIPlayer1 {
+		player = engine.Player2Turn
+	}
+	settings := Chess.AIEngine(player).Settings
+	Message.Send("Charting %v Moves", len(records))
+	go func() {
+		points, err := chartPoints(boardSize, records, settings)
+		if err != nil {
+			Message.Send(err.Error())
+			return
+		}
+		showChart(boardSize, records, points)
+	}()
+}
+
+// chartPoints replays the move records and returns the state of the game at the start and after every move.
+func chartPoints(boardSize int, records []engine.MoveRecord, settings engine.Settings) ([]chartPoint, error) {
+	solver := engine.NewSolver()
+	g := engine.NewGame(boardSize)
+	point := func() chartPoint {
+		p := g.Position()
+		return chartPoint{
+			margin: p.Player1Score - p.Player2Score,
+			win:    engine.WinProbability(context.Background(), solver, settings.SolverThreshold, p, ChartRollouts),
+		}
+	}
+	points := []chartPoint{point()}
+	for _, r := range records {
+		if _, err := g.Play(r.MoveEdge); err != nil {
+			return nil, err
+		}
+		points = append(points, point())
+	}
+	return points, nil
+}
+
+// showChart opens the chart window of the points.
+func showChart(boardSize int, records []engine.MoveRecord, points []chartPoint) {
+	w := fyne.CurrentApp().NewWindow("Game Chart")
+	boxes := max(len(engine.NewTopology(boardSize).Boxes), 1)
+	steps := max(len(points)-1, 1)
+	x := func(step int) float32 { return ChartMargin + float32(step)*ChartWidth/float32(steps) }
+	yMargin := func(margin int) float32 {
+		return ChartMargin + ChartHeight/2 - float32(margin)*ChartHeight/2/float32(boxes)
+	}
+	yWin := func(win float64) float32 { return ChartMargin + ChartHeight - float32(win)*ChartHeight }
+	foreground := theme.Color(theme.ColorNameForeground)
+
+	plot := container.NewWithoutLayout()
+	text := func(s string, c color.Color, pos fyne.Position) {
+		t := canvas.NewText(s, c)
+		t.TextSize = ChartMargin / 3
+		t.Resize(t.MinSize())
+		t.Move(pos)
+		plot.Add(t)
+	}
+	line := func(x1, y1, x2, y2 float32, c color.Color, width float32) {
+		l := canvas.NewLine(c)
+		l.Position1, l.Position2 = fyne.NewPos(x1, y1), fyne.NewPos(x2, y2)
+		l.StrokeWidth = width
+		plot.Add(l)
+	}
+
+	// Axes, the line of a draw, and the legend
+	line(ChartMargin, ChartMargin, ChartMargin, ChartMargin+ChartHeight, foreground, 1)
+	line(ChartMargin, yMargin(0), ChartMargin+ChartWidth, yMargin(0), foreground, 1)
+	text(fmt.Sprintf("+%v", boxes), ChartMarginColor, fyne.NewPos(0, ChartMargin-ChartMargin/3))
+	text(fmt.Sprintf("-%v", boxes), ChartMarginColor, fyne.NewPos(0, ChartMargin+ChartHeight-ChartMargin/3))
+	text("Player1 - Player2 Score", ChartMarginColor, fyne.NewPos(ChartMargin, ChartMargin/4))
+	text("Player1 Win Probability (Rollouts)", ChartWinColor, fyne.NewPos(ChartMargin+ChartWidth/2, ChartMargin/4))
+	text(fmt.Sprintf("Step %v", len(points)-1), foreground, fyne.NewPos(ChartMargin+ChartWidth-ChartMargin, ChartMargin+ChartHeight+ChartMargin/4))
+
+	for i, p := range points {
+		if i > 0 {
+			prev := points[i-1]
+			line(x(i-1), yMargin(prev.margin), x(i), yMargin(p.margin), ChartMarginColor, 2)
+			line(x(i-1), yWin(prev.win), x(i), yWin(p.win), ChartWinColor, 2)
+		}
+		for _, point := range []struct {
+			y float32
+			c color.Color
+		}{{yMargin(p.margin), ChartMarginColor}, {yWin(p.win), ChartWinColor}} {
+			dot := canvas.NewCircle(point.c)
+			dot.Resize(fyne.NewSize(2*chartPointRadius, 2*chartPointRadius))
+			dot.Move(fyne.NewPos(x(i)-chartPointRadius, point.y-chartPointRadius))
+			plot.Add(dot)
+		}
+	}
+
+	// Tapping a step recovers the game at that step
+	for step := range points {
+		column := newChartColumn(func() {
+			globalLock.Lock()
+			defer globalLock.Unlock()
+			if Network.Joined() {
+				Message.Send(NetworkHostOnlyMessage)
+				return
+			}
+			defer game.Refresh()
+			Chess.BoardSize = boardSize
+			game.Recover(records[:step])
+			Message.Send("Jump to Step %v, Score Difference: %+d, Player1 Win Probability (Rollouts): %.0f%%",
+				step, points[step].margin, points[step].win*100)
+		})
+		width := float32(ChartWidth) / float32(steps)
+		column.Resize(fyne.NewSize(width, ChartHeight))
+		column.Move(fyne.NewPos(x(step)-width/2, ChartMargin))
+		plot.Add(column)
+	}
+
+	export := widget.NewButton("Export PNG", func() {
+		if len(records) == 0 {
+			return
+		}
+		img := w.Canvas().Capture()
+		buf := new(bytes.Buffer)
+		if err := png.Encode(buf, img); err != nil {
+			Message.Send(err.Error())
+			return
+		}
+		fileName := GameName(records) + ChartFileSuffix
+		if err := os.WriteFile(fileName, buf.Bytes(), 0666); err != nil {
+			Message.Send(err.Error())
+			return
+		}
+		Message.Send("Chart saved to %v", fileName)
+	})
+
+	size := fyne.NewSize(ChartWidth+2*ChartMargin, ChartHeight+2*ChartMargin)
+	w.SetContent(container.NewBorder(nil, export, nil, nil, container.NewGridWrap(size, plot)))
+	w.Show()
+}
@@ -0,0 +1,52 @@
+// Command ctl sends a command to a running instance of the game over its control socket, and prints the reply.
+// It exits with an error if the command fails, so that demos and tests can be scripted.
+// See control.go for the commands.
+//
+// Usage:
+//
+//	go run ./cmd/ctl restart 5
+//	go run ./cmd/ctl ai 2 on
+//	go run ./cmd/ctl move a1-b1
+//	go run ./cmd/ctl wait 1m
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+)
+
+func main() {
+	socket := flag.String("socket", "control.sock", "control socket of the game, in the directory it was started from")
+	flag.Parse()
+	if flag.NArg() == 0 {
+		log.Fatalf("usage: ctl [-socket path] <command> [args...]")
+	}
+
+	c, err := net.Dial("unix", *socket)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer c.Close()
+	if _, err := fmt.Fprintln(c, strings.Join(flag.Args(), " ")); err != nil {
+		log.Fatal(err)
+	}
+	scanner := bufio.NewScanner(c)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "ok" {
+			return
+		}
+		if reason, ok := strings.CutPrefix(line, "error "); ok {
+			log.Fatal(reason)
+		}
+		fmt.Println(line)
+	}
+	if err := scanner.Err(); err != nil {
+		log.Fatal(err)
+	}
+	log.Fatal("connection closed before the reply ended")
+}
@@ -0,0 +1,28 @@
+// Command engine runs the engine of the game over the text engine protocol on standard input and output,
+// so that other programs can play against it. See engine.ServeProtocol for the commands.
+//
+// Usage:
+//
+//	go run ./cmd/engine -strategy MCTS -goroutines 4
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"github.com/HuXin0817/dots-and-boxes/engine"
+)
+
+func main() {
+	settings := engine.DefaultSettings()
+	strategy := flag.String("strategy", engine.DefaultStrategy, "strategy of the engine")
+	flag.IntVar(&settings.Goroutines, "goroutines", settings.Goroutines, "goroutines of each search")
+	flag.DurationVar(&settings.SearchTime, "time", settings.SearchTime, "search time of go commands without limits")
+	flag.IntVar(&settings.SolverThreshold, "solver", settings.SolverThreshold, "free edges below which the endgame solver takes over, negative to disable it")
+	flag.Parse()
+
+	if err := engine.ServeProtocol(os.Stdin, os.Stdout, *strategy, settings); err != nil {
+		log.Fatal(err)
+	}
+}
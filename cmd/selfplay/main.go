@@ -0,0 +1,257 @@
+// Command selfplay runs a tournament between engine configurations and reports their results with Elo estimates.
+// Each engine is given as Strategy[/SearchTime[/Goroutines]], and every pairing plays the same number of games
+// with each engine as Player 1, on every board size.
+//
+// Usage:
+//
+//	go run ./cmd/selfplay -engines MCTS/200ms/1,MonteCarlo/200ms/1 -sizes 4,6 -games 100
+//	go run ./cmd/selfplay -engines Nimstring/100ms/1,Chain/100ms/1,MCTS/100ms/1 -mode gauntlet
+//	go run ./cmd/selfplay -engines MCTS/100ms/1,MonteCarlo/100ms/1 -games 1000 -sprt -elo0 0 -elo1 20
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/HuXin0817/dots-and-boxes/engine"
+)
+
+// Tournament modes
+const (
+	RoundRobinMode = "roundrobin" // Every engine plays every other engine
+	GauntletMode   = "gauntlet"   // The first engine plays every other engine
+)
+
+// engineConfig is an engine taking part in the tournament.
+type engineConfig struct {
+	name     string          // Specification of the engine, used as its name
+	strategy string          // Name of the strategy
+	settings engine.Settings // Search settings
+}
+
+// parseEngine parses an engine specification Strategy[/SearchTime[/Goroutines]].
+func parseEngine(spec string) (engineConfig, error) {
+	c := engineConfig{name: spec, settings: engine.DefaultSettings()}
+	c.settings.Goroutines = 1
+	fields := strings.Split(spec, "/")
+	if len(fields) > 3 {
+		return c, fmt.Errorf("too many fields in engine %q", spec)
+	}
+	c.strategy = fields[0]
+	if _, err := engine.NewStrategy(c.strategy); err != nil {
+		return c, err
+	}
+	if len(fields) > 1 {
+		d, err := time.ParseDuration(fields[1])
+		if err != nil {
+			return c, fmt.Errorf("invalid search time in engine %q: %v", spec, err)
+		}
+		c.settings.SearchTime = d
+	}
+	if len(fields) > 2 {
+		n, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return c, fmt.Errorf("invalid goroutines in engine %q: %v", spec, err)
+		}
+		c.settings.Goroutines = n
+	}
+	c.settings.Normalize()
+	return c, nil
+}
+
+// pairing is a match between two engines, on every board size.
+type pairing struct {
+	first, second int            // Indexes of the engines
+	results       map[int]result // Results of the first engine by board size
+	decision      int            // Decision of the SPRT, see sprt.decide; the pairing stops once it is not 0
+}
+
+// total returns the results of the first engine over every board size.
+func (p *pairing) total() result {
+	var r result
+	for _, s := range p.results {
+		r.wins, r.draws, r.losses = r.wins+s.wins, r.draws+s.draws, r.losses+s.losses
+	}
+	return r
+}
+
+// job is a game of the tournament.
+type job struct {
+	pairing   *pairing // Pairing of the game
+	boardSize int      // Number of dots on each side of the board
+	swapped   bool     // Whether the second engine of the pairing is Player 1
+}
+
+// playGame plays a game between two new instances of the engines and returns the final margin of Player 1.
+func playGame(boardSize int, player1, player2 engineConfig) (int, error) {
+	players := map[engine.Turn]engineConfig{engine.Player1Turn: player1, engine.Player2Turn: player2}
+	strategies := make(map[engine.Turn]engine.Strategy)
+	for turn, c := range players {
+		st, err := engine.NewStrategy(c.strategy)
+		if err != nil {
+			return 0, err
+		}
+		strategies[turn] = st
+	}
+	g := engine.NewGame(boardSize)
+	for !g.Over() {
+		turn := g.Turn()
+		e := strategies[turn].BestEdge(context.Background(), g.Position(), players[turn].settings)
+		if _, err := g.Play(e); err != nil {
+			return 0, fmt.Errorf("%v played %v: %v", players[turn].name, g.EdgeString(e), err)
+		}
+	}
+	return g.Player1Score() - g.Player2Score(), nil
+}
+
+func main() {
+	engineSpecs := flag.String("engines", "MCTS/200ms/1,MonteCarlo/200ms/1", "comma separated engines, each as Strategy[/SearchTime[/Goroutines]]")
+	mode := flag.String("mode", RoundRobinMode, "tournament mode: "+RoundRobinMode+" or "+GauntletMode+" (first engine against the others)")
+	sizes := flag.String("sizes", "4", "comma separated board sizes (dots on each side)")
+	games := flag.Int("games", 20, "games of each pairing on each board size, rounded up to an even number to alternate colors")
+	parallel := flag.Int("parallel", 0, "games played at the same time (default: CPUs divided by the goroutines of the engines)")
+	useSPRT := flag.Bool("sprt", false, "stop a pairing early once the SPRT accepts a hypothesis")
+	elo0 := flag.Float64("elo0", 0, "Elo difference of the SPRT null hypothesis")
+	elo1 := flag.Float64("elo1", 20, "Elo difference of the SPRT alternative hypothesis")
+	alpha := flag.Float64("alpha", 0.05, "SPRT false positive rate")
+	beta := flag.Float64("beta", 0.05, "SPRT false negative rate")
+	flag.Parse()
+
+	var engines []engineConfig
+	goroutines := 1
+	for _, spec := range strings.Split(*engineSpecs, ",") {
+		c, err := parseEngine(strings.TrimSpace(spec))
+		if err != nil {
+			log.Fatalf("invalid engine: %v", err)
+		}
+		engines = append(engines, c)
+		goroutines = max(goroutines, c.settings.Goroutines)
+	}
+	if len(engines) < 2 {
+		log.Fatalf("at least two engines are needed, got %v", len(engines))
+	}
+	var boardSizes []int
+	for _, s := range strings.Split(*sizes, ",") {
+		size, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil || size < 2 || size > engine.MaxBoardSize {
+			log.Fatalf("invalid board size %q", s)
+		}
+		boardSizes = append(boardSizes, size)
+	}
+	if *parallel <= 0 {
+		*parallel = max(1, runtime.NumCPU()/goroutines)
+	}
+	test := newSPRT(*elo0, *elo1, *alpha, *beta)
+
+	opponents := len(engines)
+	switch *mode {
+	case RoundRobinMode:
+	case GauntletMode:
+		opponents = 1 // Only the first engine meets the others
+	default:
+		log.Fatalf("invalid mode %q", *mode)
+	}
+	var pairings []*pairing
+	for i := range opponents {
+		for j := i + 1; j < len(engines); j++ {
+			pairings = append(pairings, &pairing{first: i, second: j, results: make(map[int]result)})
+		}
+	}
+
+	// Interleave the games of the pairings, so that every pairing progresses at the same pace
+	jobs := make(chan job)
+	go func() {
+		defer close(jobs)
+		for i := 0; i < *games; i += 2 {
+			for _, swapped := range []bool{false, true} {
+				for _, p := range pairings {
+					for _, size := range boardSizes {
+						jobs <- job{pairing: p, boardSize: size, swapped: swapped}
+					}
+				}
+			}
+		}
+	}()
+
+	var (
+		lock sync.Mutex
+		wg   sync.WaitGroup
+	)
+	start := time.Now()
+	for range *parallel {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				p := j.pairing
+				lock.Lock()
+				stopped := p.decision != 0
+				lock.Unlock()
+				if stopped {
+					continue
+				}
+
+				player1, player2 := engines[p.first], engines[p.second]
+				if j.swapped {
+					player1, player2 = player2, player1
+				}
+				margin, err := playGame(j.boardSize, player1, player2)
+				if err != nil {
+					log.Fatalf("%dx%d %v vs %v: %v", j.boardSize, j.boardSize, player1.name, player2.name, err)
+				}
+
+				lock.Lock()
+				fmt.Printf("%dx%d %v vs %v: %+d\n", j.boardSize, j.boardSize, player1.name, player2.name, margin)
+				if j.swapped {
+					margin = -margin
+				}
+				r := p.results[j.boardSize]
+				r.add(margin)
+				p.results[j.boardSize] = r
+				total := p.total()
+				if *useSPRT && p.decision == 0 {
+					if p.decision = test.decide(total); p.decision != 0 {
+						fmt.Printf("SPRT stops %v vs %v after %v games\n", engines[p.first].name, engines[p.second].name, total.games())
+					}
+				}
+				lock.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	fmt.Printf("\nResults after %v\n", time.Since(start).Round(time.Second))
+	perEngine := make([]result, len(engines))
+	for _, p := range pairings {
+		for _, size := range boardSizes {
+			fmt.Printf("%dx%d %v vs %v: %v\n", size, size, engines[p.first].name, engines[p.second].name, p.results[size])
+		}
+		total := p.total()
+		fmt.Printf("All  %v vs %v: %v\n", engines[p.first].name, engines[p.second].name, total)
+		if *useSPRT {
+			verdict := "inconclusive"
+			switch p.decision {
+			case 1:
+				verdict = fmt.Sprintf("H1 accepted, Elo >= %v", *elo1)
+			case -1:
+				verdict = fmt.Sprintf("H0 accepted, Elo <= %v", *elo0)
+			}
+			fmt.Printf("     SPRT LLR %.2f [%.2f, %.2f], %v\n", test.llr(total), test.lower, test.upper, verdict)
+		}
+		a, b := &perEngine[p.first], &perEngine[p.second]
+		a.wins, a.draws, a.losses = a.wins+total.wins, a.draws+total.draws, a.losses+total.losses
+		b.wins, b.draws, b.losses = b.wins+total.losses, b.draws+total.draws, b.losses+total.wins
+	}
+
+	fmt.Printf("\nEngines against their opponents\n")
+	for i, c := range engines {
+		fmt.Printf("%-24v %v\n", c.name, perEngine[i])
+	}
+}
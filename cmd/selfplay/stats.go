@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// result counts the games of an engine against its opponents.
+type result struct {
+	wins, draws, losses int
+}
+
+// add records a game with the given final margin from the point of view of the engine.
+func (r *result) add(margin int) {
+	switch {
+	case margin > 0:
+		r.wins++
+	case margin < 0:
+		r.losses++
+	default:
+		r.draws++
+	}
+}
+
+// games returns the number of games played.
+func (r result) games() int { return r.wins + r.draws + r.losses }
+
+// score returns the average score per game, a draw counting as half a win, and its variance per game.
+func (r result) score() (mean, variance float64) {
+	n := float64(r.games())
+	if n == 0 {
+		return 0.5, 0
+	}
+	w, d, l := float64(r.wins)/n, float64(r.draws)/n, float64(r.losses)/n
+	mean = w + d/2
+	variance = w*(1-mean)*(1-mean) + d*(0.5-mean)*(0.5-mean) + l*mean*mean
+	return
+}
+
+// regularized returns the result with a win and a loss more, so that a one-sided result, like a clean sweep, has a
+// variance the error bars and the SPRT can work with.
+func (r result) regularized() result {
+	return result{wins: r.wins + 1, draws: r.draws, losses: r.losses + 1}
+}
+
+// elo converts an average score to an Elo difference.
+func elo(score float64) float64 {
+	score = min(max(score, 1e-6), 1-1e-6)
+	return 400 * math.Log10(score/(1-score))
+}
+
+// expectedScore converts an Elo difference to an average score.
+func expectedScore(elo float64) float64 { return 1 / (1 + math.Pow(10, -elo/400)) }
+
+// eloInterval returns the Elo difference of the result and the half width of its 95% confidence interval.
+func (r result) eloInterval() (diff, errorBar float64) {
+	mean, _ := r.score()
+	_, variance := r.regularized().score()
+	diff = elo(mean)
+	if n := r.games(); n > 0 {
+		margin := 1.96 * math.Sqrt(variance/float64(n))
+		errorBar = (elo(mean+margin) - elo(mean-margin)) / 2
+	}
+	return
+}
+
+// String returns the result with its Elo estimate.
+func (r result) String() string {
+	diff, errorBar := r.eloInterval()
+	mean, _ := r.score()
+	return fmt.Sprintf("+%v =%v -%v (%.1f%%) Elo %+.0f ± %.0f", r.wins, r.draws, r.losses, mean*100, diff, errorBar)
+}
+
+// sprt is a sequential probability ratio test of the hypothesis that the Elo difference is elo1 against elo0.
+type sprt struct {
+	elo0, elo1   float64 // Elo differences of the null and alternative hypotheses
+	lower, upper float64 // Log-likelihood ratio bounds accepting the null and the alternative hypotheses
+}
+
+// newSPRT creates a test with the given false positive and false negative rates.
+func newSPRT(elo0, elo1, alpha, beta float64) sprt {
+	return sprt{elo0: elo0, elo1: elo1, lower: math.Log(beta / (1 - alpha)), upper: math.Log((1 - beta) / alpha)}
+}
+
+// llr returns the log-likelihood ratio of the regularized result, with the normal approximation of the generalized SPRT.
+func (s sprt) llr(r result) float64 {
+	r = r.regularized()
+	mean, variance := r.score()
+	s0, s1 := expectedScore(s.elo0), expectedScore(s.elo1)
+	return float64(r.games()) * (s1 - s0) * (2*mean - s0 - s1) / (2 * variance)
+}
+
+// decide returns +1 if the alternative hypothesis is accepted, -1 if the null hypothesis is accepted, and 0 otherwise.
+func (s sprt) decide(r result) int {
+	switch llr := s.llr(r); {
+	case llr >= s.upper:
+		return 1
+	case llr <= s.lower:
+		return -1
+	default:
+		return 0
+	}
+}
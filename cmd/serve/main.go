@@ -0,0 +1,37 @@
+// Command serve runs a headless lobby hosting many games of the network game protocol at once, for tournaments.
+// Clients list the games by board size, open games against each other or against an engine, join open games,
+// quick-match with a hello and spectate any game. The app joins a quick match with Join Network Game.
+// See engine/lobby.go for the messages.
+//
+// Usage:
+//
+//	go run ./cmd/serve -addr :7290 -size 6
+//	go run ./cmd/serve -time 500ms -goroutines 2
+package main
+
+import (
+	"flag"
+	"log"
+	"net"
+
+	"github.com/HuXin0817/dots-and-boxes/engine"
+)
+
+func main() {
+	settings := engine.DefaultSettings()
+	addr := flag.String("addr", ":7290", "address on which the lobby listens")
+	size := flag.Int("size", engine.LobbyBoardSize, "board size of the quick matches that give none")
+	flag.IntVar(&settings.Goroutines, "goroutines", 1, "goroutines of each search of the engine seats")
+	flag.DurationVar(&settings.SearchTime, "time", settings.SearchTime, "search time of the engine seats")
+	flag.Parse()
+
+	l, err := net.Listen("tcp", *addr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	lobby := engine.NewLobby(settings)
+	lobby.BoardSize = *size
+	lobby.Log = log.Default()
+	log.Printf("lobby on %v", l.Addr())
+	log.Fatal(lobby.Serve(l))
+}
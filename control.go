@@ -0,0 +1,272 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/HuXin0817/dots-and-boxes/engine"
+)
+
+// The control protocol drives the game from other programs, like cmd/ctl, over the Unix socket ControlSocketFileName.
+// A client sends a command per line, and gets the lines of the reply followed by a line with ok, or a single line
+// with error and the reason. The commands are:
+//
+//	move <edge>           draw the edge, in notation, for the human to move
+//	undo                  undo the last move
+//	restart [boardSize]   restart the game, with the current board size by default, up to engine.MaxBoardSize
+//	ai <1|2> [on|off]     toggle the AI player, or turn it on or off
+//	time <duration> [1|2] set the AI search time of the player, or of both players, like 500ms
+//	position              reply the notation of the position
+//	score                 reply the scores, the turn and the step
+//	wait [timeout]        wait until the game is over and reply its result
+//
+// Every command takes globalLock like the menu actions, so it never runs in the middle of one.
+
+// ControlPollInterval is the interval at which the wait command checks the game.
+const ControlPollInterval = 100 * time.Millisecond
+
+// controlCommand runs a command of the control protocol with its arguments and returns the lines of its reply.
+type controlCommand func(args []string) ([]string, error)
+
+// controlCommands are the commands of the control protocol by name.
+var controlCommands = map[string]controlCommand{
+	"move":     controlMove,
+	"undo":     controlUndo,
+	"restart":  controlRestart,
+	"ai":       controlAI,
+	"time":     controlTime,
+	"position": controlPosition,
+	"score":    controlScore,
+	"wait":     controlWait,
+}
+
+// ServeControl serves the control protocol on the Unix socket at path, unless another instance already does.
+func ServeControl(path string) {
+	if c, err := net.Dial("unix", path); err == nil {
+		_ = c.Close()
+		Message.Send("Control Socket %v Is Used by Another Instance", path)
+		return
+	}
+	_ = os.Remove(path) // Left by an instance that did not exit cleanly
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		Message.Send(err.Error())
+		return
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		Message.Send(err.Error())
+	}
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			Message.Send(err.Error())
+			return
+		}
+		go serveControlClient(c)
+	}
+}
+
+// serveControlClient runs the commands of a client until it disconnects.
+func serveControlClient(c net.Conn) {
+	defer c.Close()
+	scanner := bufio.NewScanner(c)
+	w := bufio.NewWriter(c)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		var lines []string
+		err := fmt.Errorf("unknown command %q", fields[0])
+		if command, ok := controlCommands[fields[0]]; ok {
+			lines, err = command(fields[1:])
+		}
+		for _, line := range lines {
+			fmt.Fprintln(w, line)
+		}
+		if err != nil {
+			fmt.Fprintln(w, "error", err)
+		} else {
+			fmt.Fprintln(w, "ok")
+		}
+		if err := w.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// controlMove draws an edge like a click on its button, which is refused while the AI, the other side of a network
+// game or a browser is to move.
+func controlMove(args []string) ([]string, error) {
+	if len(args) != 1 {
+		return nil, errors.New("usage: move <edge>")
+	}
+	globalLock.Lock()
+	defer globalLock.Unlock()
+	defer game.Refresh()
+	g := game.Game()
+	e, err := g.ParseEdge(args[0])
+	if err != nil {
+		return nil, err
+	}
+	if err := g.Validate(e); err != nil {
+		return nil, err
+	}
+	if IsAIPlayer(g.Turn()) || IsRemotePlayer(g.Turn()) {
+		return nil, fmt.Errorf("%v is not played by a human of this instance", g.Turn())
+	}
+	Network.Play(game, e)
+	return nil, nil
+}
+
+// controlUndo undoes the last move like UndoMenuItem.
+func controlUndo([]string) ([]string, error) {
+	globalLock.Lock()
+	defer globalLock.Unlock()
+	defer game.Refresh()
+	if Network.Joined() {
+		return nil, errors.New(NetworkHostOnlyMessage)
+	}
+	if game.Game().Step() == 0 {
+		return nil, errors.New("no move to undo")
+	}
+	game.Undo()
+	return nil, nil
+}
+
+// controlRestart restarts the game like RestartGameMenuItem, or with another board size like the board size items.
+func controlRestart(args []string) ([]string, error) {
+	globalLock.Lock()
+	defer globalLock.Unlock()
+	defer game.Refresh()
+	if Network.Joined() {
+		return nil, errors.New(NetworkHostOnlyMessage)
+	}
+	size := Chess.BoardSize
+	if len(args) > 0 {
+		var err error
+		if size, err = strconv.Atoi(args[0]); err != nil || size < MinBoardSize || size > engine.MaxBoardSize {
+			return nil, fmt.Errorf("invalid board size %q", args[0])
+		}
+	}
+	game.Restart(size)
+	return nil, nil
+}
+
+// controlAI toggles an AI player like AIPlayer1MenuItem and AIPlayer2MenuItem, or turns it on or off.
+func controlAI(args []string) ([]string, error) {
+	if len(args) == 0 || len(args) > 2 {
+		return nil, errors.New("usage: ai <1|2> [on|off]")
+	}
+	globalLock.Lock()
+	defer globalLock.Unlock()
+	defer game.Refresh()
+	player, err := parsePlayer(args[0])
+	if err != nil {
+		return nil, err
+	}
+	enabled, toggle := Chess.AIPlayer1, game.StartAIPlayer1
+	if player == engine.Player2Turn {
+		enabled, toggle = Chess.AIPlayer2, game.StartAIPlayer2
+	}
+	on := !enabled
+	if len(args) == 2 {
+		switch args[1] {
+		case "on":
+			on = true
+		case "off":
+			on = false
+		default:
+			return nil, fmt.Errorf("expected on or off, got %q", args[1])
+		}
+	}
+	if on != enabled {
+		toggle()
+	}
+	return nil, nil
+}
+
+// parsePlayer parses a player given by its number, 1 or 2.
+func parsePlayer(s string) (engine.Turn, error) {
+	switch s {
+	case "1":
+		return engine.Player1Turn, nil
+	case "2":
+		return engine.Player2Turn, nil
+	default:
+		return 0, fmt.Errorf("invalid player %q", s)
+	}
+}
+
+// controlTime sets the AI search time of the given player, or of both players, like the AI search time items.
+func controlTime(args []string) ([]string, error) {
+	if len(args) != 1 && len(args) != 2 {
+		return nil, errors.New("usage: time <duration> [1|2]")
+	}
+	d, err := time.ParseDuration(args[0])
+	if err != nil || d <= 0 {
+		return nil, fmt.Errorf("invalid search time %q", args[0])
+	}
+	players := []engine.Turn{engine.Player1Turn, engine.Player2Turn}
+	if len(args) == 2 {
+		t, err := parsePlayer(args[1])
+		if err != nil {
+			return nil, err
+		}
+		players = []engine.Turn{t}
+	}
+	globalLock.Lock()
+	defer globalLock.Unlock()
+	defer game.Refresh()
+	Chess.UpdateAISettings(players, func(s *engine.Settings) { s.SearchTime = d })
+	Message.Send("Now AISearchTime: Player1 %v, Player2 %v", Chess.AIPlayer1Engine.Settings.SearchTime, Chess.AIPlayer2Engine.Settings.SearchTime)
+	return nil, nil
+}
+
+// controlPosition replies the notation of the position.
+func controlPosition([]string) ([]string, error) {
+	globalLock.Lock()
+	defer globalLock.Unlock()
+	return []string{game.Game().Position().String()}, nil
+}
+
+// controlScore replies the scores, the player to move and the number of moves.
+func controlScore([]string) ([]string, error) {
+	globalLock.Lock()
+	defer globalLock.Unlock()
+	g := game.Game()
+	return []string{fmt.Sprintf("Player1Score: %v, Player2Score: %v, Turn: %v, Step: %v, Over: %v",
+		g.Player1Score(), g.Player2Score(), g.Turn(), g.Step(), g.Over())}, nil
+}
+
+// controlWait waits until the game is over, or fails after the optional timeout, and replies the result.
+func controlWait(args []string) ([]string, error) {
+	var timeout <-chan time.Time
+	if len(args) > 0 {
+		d, err := time.ParseDuration(args[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid timeout %q", args[0])
+		}
+		timeout = time.After(d)
+	}
+	for {
+		globalLock.Lock()
+		g := game.Game()
+		over, result := g.Over(), fmt.Sprintf("%v %v-%v", winMessage(g), g.Player1Score(), g.Player2Score())
+		globalLock.Unlock()
+		if over {
+			return []string{result}, nil
+		}
+		select {
+		case <-timeout:
+			return nil, errors.New("timeout")
+		case <-time.After(ControlPollInterval):
+		}
+	}
+}
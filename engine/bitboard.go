@@ -0,0 +1,220 @@
+package engine
+
+import (
+	"encoding/binary"
+	"math/rand"
+)
+
+// boxCounter is implemented by boards that keep track of the edges drawn in each box.
+type boxCounter interface {
+	EdgesCountInBox(box Box) int // Returns how many edges of the box are on the board
+}
+
+// boxMask locates the edges of a box inside a bitset.
+type boxMask struct {
+	words [4]int    // Index of the word holding each edge
+	bits  [4]uint64 // Bit of each edge inside its word
+}
+
+// bitBoardLayout holds the per-topology tables shared by every BitBoard of the same topology.
+type bitBoardLayout struct {
+	words     int       // Number of 64-bit words needed for all edges
+	boxMasks  []boxMask // Edge masks for each box, indexed by box value
+	edgeBoxes [][]int   // Box values adjacent to each edge index
+}
+
+// newBitBoardLayout precomputes the edge masks of every box of the topology.
+func newBitBoardLayout(t *Topology) *bitBoardLayout {
+	l := &bitBoardLayout{
+		words:     (t.EdgesCount() + 63) / 64,
+		boxMasks:  make([]boxMask, len(t.boxEdges)),
+		edgeBoxes: make([][]int, t.EdgesCount()),
+	}
+	for _, b := range t.Boxes {
+		for i, e := range t.BoxEdges(b) {
+			idx := t.EdgeIndex(e)
+			l.boxMasks[b].words[i] = idx / 64
+			l.boxMasks[b].bits[i] = 1 << (idx % 64)
+		}
+	}
+	for i, e := range t.Edges {
+		for _, b := range t.AdjacentBoxes(e) {
+			l.edgeBoxes[i] = append(l.edgeBoxes[i], int(b))
+		}
+	}
+	return l
+}
+
+// BitBoard is a fixed-size bitset implementation of the Board interface.
+// Every edge of the topology owns one bit, and the number of drawn edges of each box is kept up to date,
+// so Contains and EdgesCountInBox run in constant time and Clone is a plain copy of two small slices.
+type BitBoard struct {
+	t      *Topology       // Topology of the board
+	layout *bitBoardLayout // Precomputed masks of the topology
+	bits   []uint64        // One bit for each drawn edge
+	counts []uint8         // Number of drawn edges in each box, indexed by box value
+	size   int             // Number of drawn edges
+}
+
+// NewBitBoard creates a new, empty bitset board for the topology.
+func NewBitBoard(t *Topology) Board {
+	l := t.bitBoardLayout
+	return &BitBoard{
+		t:      t,
+		layout: l,
+		bits:   make([]uint64, l.words),
+		counts: make([]uint8, len(l.boxMasks)),
+	}
+}
+
+// Add adds an edge to the board.
+func (b *BitBoard) Add(e Edge) {
+	idx := b.t.EdgeIndex(e)
+	if idx < 0 {
+		return
+	}
+	word, bit := idx/64, uint64(1)<<(idx%64)
+	if b.bits[word]&bit != 0 {
+		return
+	}
+	b.bits[word] |= bit
+	b.size++
+	for _, box := range b.layout.edgeBoxes[idx] {
+		b.counts[box]++
+	}
+}
+
+// Remove removes an edge from the board.
+func (b *BitBoard) Remove(e Edge) {
+	if b.Contains(e) {
+		b.removeIndex(b.t.EdgeIndex(e))
+	}
+}
+
+// Contains checks if an edge is on the board.
+func (b *BitBoard) Contains(e Edge) bool {
+	idx := b.t.EdgeIndex(e)
+	if idx < 0 {
+		return false
+	}
+	return b.bits[idx/64]&(1<<(idx%64)) != 0
+}
+
+// Size returns the number of edges on the board.
+func (b *BitBoard) Size() int { return b.size }
+
+// Clone creates a copy of the board.
+func (b *BitBoard) Clone() Board {
+	return &BitBoard{
+		t:      b.t,
+		layout: b.layout,
+		bits:   append([]uint64(nil), b.bits...),
+		counts: append([]uint8(nil), b.counts...),
+		size:   b.size,
+	}
+}
+
+// CopyFrom overwrites the board with the content of src, reusing its memory.
+func (b *BitBoard) CopyFrom(src *BitBoard) {
+	copy(b.bits, src.bits)
+	copy(b.counts, src.counts)
+	b.size = src.size
+}
+
+// EdgesCountInBox returns how many edges of the box are on the board.
+func (b *BitBoard) EdgesCountInBox(box Box) int {
+	if box < 0 || int(box) >= len(b.counts) {
+		return 0
+	}
+	return int(b.counts[box])
+}
+
+// FreeEdgeInBox returns an edge of the box that is not on the board yet, or InvalidEdge if the box is complete.
+func (b *BitBoard) FreeEdgeInBox(box Box) Edge {
+	m := b.layout.boxMasks[box]
+	for i, e := range b.t.BoxEdges(box) {
+		if b.bits[m.words[i]]&m.bits[i] == 0 {
+			return e
+		}
+	}
+	return InvalidEdge
+}
+
+// addIndex draws the free edge at index idx and returns the number of boxes it completed.
+func (b *BitBoard) addIndex(idx int) (captured int) {
+	b.bits[idx>>6] |= 1 << (idx & 63)
+	b.size++
+	for _, box := range b.layout.edgeBoxes[idx] {
+		b.counts[box]++
+		if b.counts[box] == 4 {
+			captured++
+		}
+	}
+	return
+}
+
+// removeIndex erases the drawn edge at index idx.
+func (b *BitBoard) removeIndex(idx int) {
+	b.bits[idx>>6] &^= 1 << (idx & 63)
+	b.size--
+	for _, box := range b.layout.edgeBoxes[idx] {
+		b.counts[box]--
+	}
+}
+
+// nextEdge is the bitset version of getNextEdges, working directly on edge indexes and box counters.
+func (b *BitBoard) nextEdge(r *rand.Rand) Edge {
+	edges := b.t.Edges
+	enemyMinScore, best := 3, -1
+	offset := r.Intn(len(edges))
+	for i := range edges {
+		idx := i + offset
+		if idx >= len(edges) {
+			idx -= len(edges)
+		}
+		if b.bits[idx>>6]&(1<<(idx&63)) != 0 {
+			continue
+		}
+		enemyScore := 0
+		for _, box := range b.layout.edgeBoxes[idx] {
+			switch b.counts[box] {
+			case 3:
+				return edges[idx] // Immediately return if it obtains a score
+			case 2:
+				enemyScore++ // Increment if the opponent could score here
+			}
+		}
+		if enemyMinScore > enemyScore {
+			enemyMinScore = enemyScore
+			best = idx
+		}
+	}
+	if best < 0 {
+		return InvalidEdge
+	}
+	return edges[best]
+}
+
+// edgeClass is the bitset version of the function edgeClass of mcts.go, classifying the edge at index idx from the box
+// counters.
+func (b *BitBoard) edgeClass(idx int) int {
+	class := safeEdge
+	for _, box := range b.layout.edgeBoxes[idx] {
+		switch b.counts[box] {
+		case 3:
+			return captureEdge
+		case 2:
+			class = unsafeEdge
+		}
+	}
+	return class
+}
+
+// key returns a string identifying the set of edges drawn on the board.
+func (b *BitBoard) key() string {
+	key := make([]byte, 8*len(b.bits))
+	for i, w := range b.bits {
+		binary.LittleEndian.PutUint64(key[8*i:], w)
+	}
+	return string(key)
+}
@@ -0,0 +1,88 @@
+package engine
+
+import (
+	"math/rand"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+// TestBitBoardMatchesMapBoard plays random games on both boards and checks that they agree after every move.
+func TestBitBoardMatchesMapBoard(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for size := 2; size <= 7; size++ {
+		tp := NewTopology(size)
+		for game := 0; game < 20; game++ {
+			bitBoard, mapBoard := NewBitBoard(tp), NewBoard()
+			var clone Board
+			for i, idx := range r.Perm(tp.EdgesCount()) {
+				e := tp.Edges[idx]
+				if !slices.Equal(tp.ObtainsBoxes(bitBoard, e), tp.ObtainsBoxes(mapBoard, e)) {
+					t.Fatalf("%vx%v: captured boxes of %v differ", size, size, tp.EdgeString(e))
+				}
+				if edgeClass(tp, bitBoard, e) != edgeClass(tp, mapBoard, e) {
+					t.Fatalf("%vx%v: class of %v differs", size, size, tp.EdgeString(e))
+				}
+				bitBoard.Add(e)
+				mapBoard.Add(e)
+				if r.Intn(4) == 0 {
+					bitBoard.Remove(e)
+					mapBoard.Remove(e)
+					checkBoards(t, tp, bitBoard, mapBoard)
+					bitBoard.Add(e)
+					mapBoard.Add(e)
+				}
+				checkBoards(t, tp, bitBoard, mapBoard)
+				if i == tp.EdgesCount()/2 {
+					clone = bitBoard.Clone()
+					checkBoards(t, tp, clone, mapBoard.Clone())
+				}
+			}
+			if clone != nil && clone.Size() != tp.EdgesCount()/2+1 {
+				t.Fatalf("%vx%v: clone has %v edges after the game went on, expected %v", size, size, clone.Size(), tp.EdgesCount()/2+1)
+			}
+		}
+	}
+}
+
+// checkBoards checks that both boards hold the same edges.
+func checkBoards(t *testing.T, tp *Topology, bitBoard, mapBoard Board) {
+	t.Helper()
+	if bitBoard.Size() != mapBoard.Size() {
+		t.Fatalf("sizes differ: bitset %v, map %v", bitBoard.Size(), mapBoard.Size())
+	}
+	for _, e := range tp.Edges {
+		if bitBoard.Contains(e) != mapBoard.Contains(e) {
+			t.Fatalf("edge %v differs", tp.EdgeString(e))
+		}
+	}
+	for box := range tp.boxEdges {
+		if tp.EdgesCountInBox(bitBoard, Box(box)) != tp.EdgesCountInBox(mapBoard, Box(box)) {
+			t.Fatalf("edges in box %v differ", box)
+		}
+	}
+}
+
+// benchmarkRollout measures full random rollouts from an empty board built by newBoard, on the usual board sizes.
+func benchmarkRollout(b *testing.B, newBoard func(t *Topology) Board) {
+	for _, size := range []int{6, 8} {
+		b.Run(strconv.Itoa(size), func(b *testing.B) {
+			t := NewTopology(size)
+			r := rand.New(rand.NewSource(1))
+			empty := newBoard(t)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				Rollout(t, empty.Clone(), r)
+			}
+		})
+	}
+}
+
+func BenchmarkRolloutMap(b *testing.B) {
+	benchmarkRollout(b, func(*Topology) Board { return NewBoard() })
+}
+
+func BenchmarkRolloutBitBoard(b *testing.B) {
+	benchmarkRollout(b, NewBitBoard)
+}
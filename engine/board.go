@@ -0,0 +1,40 @@
+package engine
+
+// Board interface defines the methods for managing the board state.
+type Board interface {
+	Add(e Edge)           // Adds an edge to the board
+	Remove(e Edge)        // Removes an edge from the board
+	Contains(e Edge) bool // Checks if an edge is on the board
+	Clone() Board         // Clones the board
+	Size() int            // Returns the size of the board
+}
+
+// board is a concrete implementation of the Board interface.
+type board map[Edge]struct{}
+
+// NewBoard creates a new, empty board.
+func NewBoard() Board { return make(board) }
+
+// Add adds an edge to the board.
+func (b board) Add(e Edge) { b[e] = struct{}{} }
+
+// Remove removes an edge from the board.
+func (b board) Remove(e Edge) { delete(b, e) }
+
+// Contains checks if an edge is on the board.
+func (b board) Contains(e Edge) bool {
+	_, ok := b[e]
+	return ok
+}
+
+// Size returns the number of edges on the board.
+func (b board) Size() int { return len(b) }
+
+// Clone creates a copy of the board.
+func (b board) Clone() Board {
+	cb := make(board, b.Size())
+	for e := range b {
+		cb.Add(e)
+	}
+	return cb
+}
@@ -0,0 +1,337 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// ground stands for the outside of the board when it is reached through a free edge on the border.
+const ground Box = -1
+
+// Chain is a sequence of open boxes linked by free edges in which every box has at most two free edges.
+// Whoever draws an edge of a chain gives all its boxes away, except for the last two which can be declined.
+type Chain struct {
+	Boxes  []Box // Boxes of the chain, in order from one end to the other
+	Loop   bool  // Whether the chain closes on itself
+	Opened bool  // Whether boxes of the chain can already be captured
+}
+
+// Long checks if the chain counts for the long chain rule, that is a chain of at least three boxes.
+func (c Chain) Long() bool { return !c.Loop && len(c.Boxes) >= 3 }
+
+// String returns a short description of the chain.
+func (c Chain) String() string {
+	kind := "chain"
+	if c.Loop {
+		kind = "loop"
+	}
+	if c.Opened {
+		kind = "opened " + kind
+	}
+	return fmt.Sprintf("%v of %v", kind, len(c.Boxes))
+}
+
+// Analysis describes the chain structure of a position.
+type Analysis struct {
+	Chains     []Chain // Chains and loops of the board, shortest first
+	LongChains int     // Number of chains of at least three boxes
+	Loops      int     // Number of loops
+	SafeEdges  int     // Number of free edges that give no box away
+	Controller Turn    // Player expected to get control of the endgame
+}
+
+// String returns a short description of the analysis.
+func (a Analysis) String() string {
+	return fmt.Sprintf("Chains: %v, Long Chains: %v, Loops: %v, Safe Edges: %v, Controller: %v",
+		len(a.Chains)-a.Loops, a.LongChains, a.Loops, a.SafeEdges, a.Controller)
+}
+
+// Analyze splits the open boxes of the position into chains and loops and predicts who gets control.
+// While safe edges remain, the controller is predicted by the long chain rule: Player 1 gets control when the number
+// of dots plus the number of long chains is even. Once only chains and loops are left, the player to move has to open
+// one of them and the other player is in control.
+func Analyze(p Position) Analysis {
+	var a Analysis
+	for _, e := range p.Edges {
+		if !p.Board.Contains(e) && edgeClass(p.Topology, p.Board, e) == safeEdge {
+			a.SafeEdges++
+		}
+	}
+	a.Chains = findChains(p.Topology, p.Board)
+	for _, c := range a.Chains {
+		if c.Long() {
+			a.LongChains++
+		}
+		if c.Loop {
+			a.Loops++
+		}
+	}
+	switch {
+	case a.SafeEdges == 0 && !a.opened():
+		a.Controller = -p.Turn
+	case (len(p.Dots)+a.LongChains)%2 == 0:
+		a.Controller = Player1Turn
+	default:
+		a.Controller = Player2Turn
+	}
+	return a
+}
+
+// opened checks if boxes can be captured in the position.
+func (a Analysis) opened() bool {
+	for _, c := range a.Chains {
+		if c.Opened {
+			return true
+		}
+	}
+	return false
+}
+
+// freeNeighbors returns the boxes reached through each free edge of the box, ground for the border.
+func freeNeighbors(t *Topology, b Board, box Box) (edges []Edge, neighbors []Box) {
+	for _, e := range t.BoxEdges(box) {
+		if b.Contains(e) {
+			continue
+		}
+		n := ground
+		for _, other := range t.AdjacentBoxes(e) {
+			if other != box {
+				n = other
+			}
+		}
+		edges = append(edges, e)
+		neighbors = append(neighbors, n)
+	}
+	return
+}
+
+// findChains returns the chains and loops of board b, shortest first.
+func findChains(t *Topology, b Board) []Chain {
+	inChain := func(box Box) bool {
+		if box == ground {
+			return false
+		}
+		c := t.EdgesCountInBox(b, box)
+		return c == 2 || c == 3
+	}
+	visited := make(map[Box]bool)
+	walk := func(start Box) (c Chain) {
+		prev, cur := ground, start
+		for {
+			visited[cur] = true
+			c.Boxes = append(c.Boxes, cur)
+			c.Opened = c.Opened || t.EdgesCountInBox(b, cur) == 3
+			next := ground
+			_, neighbors := freeNeighbors(t, b, cur)
+			for _, n := range neighbors {
+				if n != prev && inChain(n) && !visited[n] {
+					next = n
+				}
+			}
+			if next == ground {
+				for _, n := range neighbors {
+					c.Loop = c.Loop || (n == start && n != prev && len(c.Boxes) > 2)
+				}
+				return
+			}
+			prev, cur = cur, next
+		}
+	}
+
+	var chains []Chain
+	// Chains start at a box with a free edge leading out of the chain
+	for _, box := range t.Boxes {
+		if visited[box] || !inChain(box) {
+			continue
+		}
+		_, neighbors := freeNeighbors(t, b, box)
+		end := len(neighbors) == 1
+		for _, n := range neighbors {
+			end = end || !inChain(n)
+		}
+		if end {
+			chains = append(chains, walk(box))
+		}
+	}
+	// The boxes left form loops
+	for _, box := range t.Boxes {
+		if !visited[box] && inChain(box) {
+			chains = append(chains, walk(box))
+		}
+	}
+	sort.SliceStable(chains, func(i, j int) bool { return len(chains[i].Boxes) < len(chains[j].Boxes) })
+	return chains
+}
+
+// sharedFreeEdge returns the free edge between box and n, where n may be ground.
+func sharedFreeEdge(t *Topology, b Board, box, n Box) Edge {
+	edges, neighbors := freeNeighbors(t, b, box)
+	for i := range edges {
+		if neighbors[i] == n {
+			return edges[i]
+		}
+	}
+	return InvalidEdge
+}
+
+// controlledValue estimates the margin the player in control gets from the chains,
+// keeping control with a sacrifice of two boxes for each long chain and four for each loop except the last one,
+// which is a chain whenever there is one since the opponent opens the loops first.
+// Short chains are left out since neither player can keep control through them.
+func controlledValue(chains []Chain) int {
+	value, last := 0, 0
+	for _, c := range chains {
+		switch {
+		case c.Loop:
+			value += len(c.Boxes) - 8
+			if last == 0 {
+				last = 8
+			}
+		case c.Long():
+			value += len(c.Boxes) - 4
+			last = 4
+		}
+	}
+	return value + last
+}
+
+// chainPlayer plays the endgame of chains and loops: it takes what the opponent offers, declines the last two boxes
+// of a chain or the last four of a loop when keeping control is worth it, and otherwise opens the cheapest chain.
+type chainPlayer struct {
+	solver *Solver // Endgame solver, used once few edges are left
+}
+
+// BestEdge returns the edge chosen from the chain analysis, searching with flat Monte Carlo while safe edges remain.
+func (c *chainPlayer) BestEdge(ctx context.Context, p Position, s Settings) Edge {
+	if p.Board.Size() >= p.EdgesCount() {
+		return InvalidEdge
+	}
+	if e, ok := solveEndgame(ctx, c.solver, p, s); ok {
+		return e
+	}
+	if e, ok := c.plannedEdge(p); ok {
+		return e
+	}
+	return GetBestEdge(ctx, p.Topology, p.Board, s.Goroutines, s.SearchTime)
+}
+
+// plannedEdge returns the edge chosen from the chain analysis, or false while safe edges remain and no box is offered.
+func (c *chainPlayer) plannedEdge(p Position) (Edge, bool) {
+	a := Analyze(p)
+	var opened []Chain
+	for _, ch := range a.Chains {
+		if ch.Opened {
+			opened = append(opened, ch)
+		}
+	}
+	if len(opened) > 0 {
+		return offeredEdge(p.Topology, p.Board, a, opened), true
+	}
+	if a.SafeEdges > 0 {
+		return InvalidEdge, false
+	}
+	return sacrificeEdge(p.Topology, p.Board, a.Chains), true
+}
+
+// RankEdges ranks the edges with flat Monte Carlo, putting the edge chosen from the chain analysis first.
+func (c *chainPlayer) RankEdges(ctx context.Context, p Position, s Settings) []EdgeValue {
+	return rankPlanned(ctx, c.solver, p, s, c.plannedEdge)
+}
+
+// Ponder solves the endgame ahead, the chain analysis being cheap enough to run again after the opponent's move.
+func (c *chainPlayer) Ponder(ctx context.Context, p Position, s Settings) {
+	ponderEndgame(ctx, c.solver, p, s)
+}
+
+// offeredEdge returns the edge to draw when the opponent has offered boxes: a capture,
+// or a double-dealing move when every offered box but the last two of a chain or four of a loop has been taken.
+func offeredEdge(t *Topology, b Board, a Analysis, opened []Chain) Edge {
+	// Keep the chain that may be declined for last
+	sort.SliceStable(opened, func(i, j int) bool { return declinable(t, b, opened[i]) < declinable(t, b, opened[j]) })
+	c := opened[len(opened)-1]
+	if len(opened) == 1 {
+		var rest []Chain
+		for _, ch := range a.Chains {
+			if !ch.Opened {
+				rest = append(rest, ch)
+			}
+		}
+		if sacrifice := declinable(t, b, c); sacrifice > 0 && controlledValue(rest) > sacrifice {
+			return doubleDealingEdge(t, b, c)
+		}
+	}
+	c = opened[0]
+	for _, end := range []Box{c.Boxes[0], c.Boxes[len(c.Boxes)-1]} {
+		if t.EdgesCountInBox(b, end) == 3 {
+			edges, _ := freeNeighbors(t, b, end)
+			return edges[0]
+		}
+	}
+	return InvalidEdge
+}
+
+// declinable returns the number of boxes given away by declining the rest of an opened chain:
+// two for the last two boxes of a chain and four for the last four boxes of a loop, or zero if it cannot be declined.
+func declinable(t *Topology, b Board, c Chain) int {
+	first, last := t.EdgesCountInBox(b, c.Boxes[0]) == 3, t.EdgesCountInBox(b, c.Boxes[len(c.Boxes)-1]) == 3
+	switch {
+	case first && last && len(c.Boxes) == 4:
+		return 4
+	case first != last && len(c.Boxes) == 2:
+		return 2
+	}
+	return 0
+}
+
+// doubleDealingEdge returns the edge that leaves the rest of an opened chain to the opponent as dominoes:
+// the far edge of the last box of a chain, or the middle edge of the last four boxes of a loop.
+func doubleDealingEdge(t *Topology, b Board, c Chain) Edge {
+	if len(c.Boxes) == 4 {
+		return sharedFreeEdge(t, b, c.Boxes[1], c.Boxes[2])
+	}
+	far, near := c.Boxes[0], c.Boxes[1]
+	if t.EdgesCountInBox(b, far) == 3 {
+		far, near = near, far
+	}
+	edges, neighbors := freeNeighbors(t, b, far)
+	for i := range edges {
+		if neighbors[i] != near {
+			return edges[i]
+		}
+	}
+	return InvalidEdge
+}
+
+// sacrificeEdge returns the edge opening the chain that costs the least once no safe edge is left.
+// Short chains come first since the opponent cannot keep control through them, and a chain of two is opened
+// in the middle so that it cannot be declined. Then the chain or loop with the smallest gain for the opponent
+// keeping control is opened.
+func sacrificeEdge(t *Topology, b Board, chains []Chain) Edge {
+	if len(chains) == 0 {
+		return getNextEdges(t, b, rand.New(rand.NewSource(time.Now().UnixNano())))
+	}
+	cost := func(c Chain) int {
+		switch {
+		case c.Loop:
+			return len(c.Boxes) - 8
+		case c.Long():
+			return len(c.Boxes) - 4
+		}
+		return len(c.Boxes) - 100
+	}
+	sort.SliceStable(chains, func(i, j int) bool { return cost(chains[i]) < cost(chains[j]) })
+	c := chains[0]
+	if len(c.Boxes) > 1 {
+		return sharedFreeEdge(t, b, c.Boxes[0], c.Boxes[1])
+	}
+	edges, neighbors := freeNeighbors(t, b, c.Boxes[0])
+	for i := range edges {
+		if neighbors[i] == ground {
+			return edges[i]
+		}
+	}
+	return edges[0]
+}
@@ -0,0 +1,89 @@
+package engine
+
+import (
+	"slices"
+	"testing"
+)
+
+// freeEdgesPosition returns a position of the board of the given size where every edge but the free ones is drawn.
+func freeEdgesPosition(t *testing.T, size int, free ...string) Position {
+	t.Helper()
+	tp := NewTopology(size)
+	b := NewBoard()
+	for _, e := range tp.Edges {
+		b.Add(e)
+	}
+	for _, s := range free {
+		e, err := tp.ParseEdge(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b.Remove(e)
+	}
+	return Position{Topology: tp, Board: b, Turn: Player1Turn}
+}
+
+func TestAnalyzeChains(t *testing.T) {
+	for _, c := range []struct {
+		name       string
+		free       []string
+		chains     []string
+		longChains int
+		loops      int
+	}{
+		{"3-chain", []string{"a1-a2", "b1-b2", "c1-c2", "d1-d2"}, []string{"chain of 3"}, 1, 0},
+		{"4-loop", []string{"b1-b2", "a2-b2", "b2-c2", "b2-b3"}, []string{"loop of 4"}, 0, 1},
+		{
+			"2-chain and 3-chain",
+			[]string{"a1-a2", "b1-b2", "b1-c1", "a3-a4", "b3-b4", "c3-c4", "d3-d4"},
+			[]string{"chain of 2", "chain of 3"}, 1, 0,
+		},
+	} {
+		p := freeEdgesPosition(t, 4, c.free...)
+		a := Analyze(p)
+		var chains []string
+		for _, ch := range a.Chains {
+			chains = append(chains, ch.String())
+		}
+		if !slices.Equal(chains, c.chains) || a.LongChains != c.longChains || a.Loops != c.loops {
+			t.Errorf("%v: %v, %v", c.name, chains, a)
+		}
+		if a.SafeEdges != 0 || a.Controller != Player2Turn {
+			t.Errorf("%v: the player to move has to open a chain, got %v", c.name, a)
+		}
+	}
+}
+
+func TestOfferedEdge(t *testing.T) {
+	for _, c := range []struct {
+		name  string
+		free  []string
+		edges []string // Edges that may be drawn
+	}{
+		// Nothing is left to keep control for, so the 2-chain is taken whole
+		{"2-chain taken", []string{"b1-b2", "b1-c1"}, []string{"b1-b2"}},
+		// Declining the 2-chain costs 2 boxes and keeps control of the 3-chain
+		{"2-chain declined", []string{"b1-b2", "b1-c1", "a3-a4", "b3-b4", "c3-c4", "d3-d4"}, []string{"b1-c1"}},
+		// The end of an opened 3-chain is taken, the last two boxes being declined later if worth it
+		{"3-chain", []string{"b1-b2", "c1-c2", "d1-d2", "a3-a4", "b3-b4", "c3-c4", "d3-d4"}, []string{"b1-b2"}},
+		// Declining the 4 boxes of the opened loop costs more than the 3-chain is worth, so the loop is taken
+		{"open loop taken", []string{"a2-b2", "b2-c2", "b2-b3", "d1-d2", "c2-d2", "c3-d3", "c4-d4"}, []string{"a2-b2", "b2-c2"}},
+		// Declining the 4 boxes of the opened loop keeps control of the 5-chain
+		{
+			"open loop declined",
+			[]string{"a2-b2", "b2-c2", "b2-b3", "c1-d1", "c2-d2", "c3-d3", "c3-c4", "b3-b4", "a3-a4"},
+			[]string{"b2-b3"},
+		},
+	} {
+		p := freeEdgesPosition(t, 4, c.free...)
+		e, ok := (&chainPlayer{}).plannedEdge(p)
+		if !ok || !slices.Contains(c.edges, p.EdgeString(e)) {
+			t.Errorf("%v: played %v, expected one of %v", c.name, p.EdgeString(e), c.edges)
+			continue
+		}
+		memo := make(map[uint64]int)
+		if moveMargin(p.Topology, p.Board, e, memo) != minimax(p.Topology, p.Board, memo) {
+			t.Errorf("%v: %v is not optimal", c.name, p.EdgeString(e))
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package engine
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ExternalStrategy is the name of the strategy played by an external engine process. It is not registered,
+// since the engine needs a command line, see NewExternalEngine.
+const ExternalStrategy = "External"
+
+// ExternalEngineTimeout is how long an external engine may take to answer beyond the search time.
+const ExternalEngineTimeout = 10 * time.Second
+
+// ExternalEngine is a strategy played by an external engine process over the engine protocol, see ServeProtocol.
+// When the engine fails to answer with a legal edge, the rollout policy plays instead and Err reports why.
+type ExternalEngine struct {
+	Name       string         // Name replied by the engine to dab
+	cmd        *exec.Cmd      // Engine process, nil if the engine is not a process of its own
+	in         io.WriteCloser // Standard input of the engine
+	lines      chan string    // Lines written by the engine, closed when it exits
+	lock       sync.Mutex     // Mutex for search synchronization
+	goroutines int            // Goroutines of the last setoption sent, 0 if none
+	err        error          // Last error of the engine
+}
+
+// NewExternalEngine starts an external engine process with the given command line and waits for it to identify itself.
+func NewExternalEngine(command string, args ...string) (*ExternalEngine, error) {
+	cmd := exec.Command(command, args...)
+	cmd.Stderr = os.Stderr
+	in, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return newExternalEngine(command, cmd, in, out)
+}
+
+// newExternalEngine talks to an engine over in and out, the standard input and output of the engine process cmd,
+// if not nil, and waits for the engine to identify itself.
+func newExternalEngine(name string, cmd *exec.Cmd, in io.WriteCloser, out io.Reader) (*ExternalEngine, error) {
+	x := &ExternalEngine{Name: name, cmd: cmd, in: in, lines: make(chan string, 64)}
+	go func() {
+		defer close(x.lines)
+		scanner := bufio.NewScanner(out)
+		for scanner.Scan() {
+			x.lines <- scanner.Text()
+		}
+	}()
+
+	if err := x.send("dab"); err != nil {
+		x.Close()
+		return nil, err
+	}
+	deadline := time.After(ExternalEngineTimeout)
+	for {
+		line, err := x.receive(deadline)
+		if err != nil {
+			x.Close()
+			return nil, fmt.Errorf("%v: %v", name, err)
+		}
+		if name, ok := strings.CutPrefix(line, "id name "); ok {
+			x.Name = name
+		}
+		if line == "dabok" {
+			return x, nil
+		}
+	}
+}
+
+// send writes a command to the engine.
+func (x *ExternalEngine) send(format string, a ...any) error {
+	_, err := fmt.Fprintf(x.in, format+"\n", a...)
+	return err
+}
+
+// receive returns the next line written by the engine, or an error if the engine exits or the deadline passes.
+func (x *ExternalEngine) receive(deadline <-chan time.Time) (string, error) {
+	select {
+	case line, ok := <-x.lines:
+		if !ok {
+			return "", fmt.Errorf("engine exited")
+		}
+		return line, nil
+	case <-deadline:
+		return "", fmt.Errorf("engine timed out")
+	}
+}
+
+// BestEdge sends position p to the engine and returns the edge it answers within the search time of the settings.
+// If ctx is done first, the engine is told to stop and its answer is returned.
+func (x *ExternalEngine) BestEdge(ctx context.Context, p Position, s Settings) Edge {
+	if p.Board.Size() >= p.EdgesCount() {
+		return InvalidEdge
+	}
+	x.lock.Lock()
+	defer x.lock.Unlock()
+	e, err := x.search(ctx, p, s)
+	if err != nil {
+		x.err = fmt.Errorf("%v: %v", x.Name, err)
+		return getNextEdges(p.Topology, p.Board, rand.New(rand.NewSource(time.Now().UnixNano())))
+	}
+	x.err = nil
+	return e
+}
+
+// search runs a search of the engine. It must be called with lock held.
+func (x *ExternalEngine) search(ctx context.Context, p Position, s Settings) (Edge, error) {
+	// Skip the answers left over by a failed search
+	if err := x.send("stop"); err != nil {
+		return InvalidEdge, err
+	}
+	if err := x.send("isready"); err != nil {
+		return InvalidEdge, err
+	}
+	deadline := time.After(ExternalEngineTimeout)
+	for {
+		line, err := x.receive(deadline)
+		if err != nil {
+			return InvalidEdge, err
+		}
+		if line == "readyok" {
+			break
+		}
+	}
+
+	if x.goroutines != s.Goroutines {
+		if err := x.send("setoption name Goroutines value %v", s.Goroutines); err != nil {
+			return InvalidEdge, err
+		}
+		x.goroutines = s.Goroutines
+	}
+	if err := x.send("position notation %v", p); err != nil {
+		return InvalidEdge, err
+	}
+	if err := x.send("go movetime %v", max(s.SearchTime.Milliseconds(), 1)); err != nil {
+		return InvalidEdge, err
+	}
+
+	done := ctx.Done()
+	deadline = time.After(s.SearchTime + ExternalEngineTimeout)
+	for {
+		select {
+		case <-done:
+			done = nil // Stop once, then wait for the answer
+			if err := x.send("stop"); err != nil {
+				return InvalidEdge, err
+			}
+			deadline = time.After(ExternalEngineTimeout)
+			continue
+		case line, ok := <-x.lines:
+			if !ok {
+				return InvalidEdge, fmt.Errorf("engine exited")
+			}
+			if msg, found := strings.CutPrefix(line, "info string "); found {
+				return InvalidEdge, fmt.Errorf("%v", msg)
+			}
+			answer, found := strings.CutPrefix(line, "bestmove ")
+			if !found {
+				continue
+			}
+			e, err := p.ParseEdge(answer)
+			if err != nil {
+				return InvalidEdge, err
+			}
+			if p.Board.Contains(e) {
+				return InvalidEdge, fmt.Errorf("illegal edge %v", answer)
+			}
+			return e, nil
+		case <-deadline:
+			return InvalidEdge, fmt.Errorf("engine timed out")
+		}
+	}
+}
+
+// Err returns why the last search of the engine failed, or nil if it succeeded.
+func (x *ExternalEngine) Err() error {
+	x.lock.Lock()
+	defer x.lock.Unlock()
+	return x.err
+}
+
+// Close tells the engine to quit and kills it if it does not exit in time.
+func (x *ExternalEngine) Close() error {
+	x.send("quit")
+	x.in.Close()
+	if x.cmd == nil {
+		return nil
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- x.cmd.Wait() }()
+	select {
+	case err := <-exited:
+		return err
+	case <-time.After(ExternalEngineTimeout):
+		x.cmd.Process.Kill()
+		return <-exited
+	}
+}
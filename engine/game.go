@@ -0,0 +1,247 @@
+package engine
+
+import (
+	"errors"
+	"time"
+)
+
+// Turn represents the current player's turn
+type Turn int
+
+const (
+	Player1Turn Turn = 1            // Constant for Player 1's turn
+	Player2Turn      = -Player1Turn // Constant for Player 2's turn
+)
+
+// String returns the string representation of the current turn.
+func (t Turn) String() string {
+	if t == Player1Turn {
+		return "Player1"
+	} else {
+		return "Player2"
+	}
+}
+
+// ChangeTurn switches the current turn to the other player.
+func ChangeTurn(t *Turn) { *t = -*t }
+
+// MoveRecord records a move in the game.
+type MoveRecord struct {
+	TimeStamp    time.Time `json:"timeStamp"`    // The timestamp of the move
+	Step         int       `json:"step"`         // The step number of the move
+	Player       Turn      `json:"player"`       // The player who made the move
+	MoveEdge     Edge      `json:"moveEdge"`     // The edge that was moved
+	Move         string    `json:"move"`         // The notation of the edge, which takes precedence over MoveEdge when replayed
+	Player1Score int       `json:"player1Score"` // The score of Player 1 before the move
+	Player2Score int       `json:"player2Score"` // The score of Player 2 before the move
+}
+
+var (
+	ErrInvalidEdge = errors.New("edge is not on the board") // Returned when an edge does not belong to the topology
+	ErrEdgeDrawn   = errors.New("edge is already drawn")    // Returned when an edge has already been drawn
+	ErrGameOver    = errors.New("game is over")             // Returned when a move is made after the game ended
+)
+
+// Game holds the complete state of a single game: its topology, board, turn, scores and move history.
+// A Game is not safe for concurrent use; callers must provide their own synchronization.
+type Game struct {
+	*Topology
+	board        Board        // Current state of the board
+	turn         Turn         // Current turn of the game
+	player1Score int          // Score of Player 1
+	player2Score int          // Score of Player 2
+	boxOwners    map[Box]Turn // Player who completed each box
+	records      []MoveRecord // Records of moves
+	deltas       []Delta      // Changes made by the moves, in the order of the records
+}
+
+// Delta is the change a move made to the game, which is enough to undo the move without replaying the game.
+type Delta struct {
+	Edge        Edge  // Edge drawn by the move
+	Player      Turn  // Player who drew the edge
+	Boxes       []Box // Boxes completed by the edge, a point each for the player
+	TurnChanged bool  // Whether the turn passed to the other player
+}
+
+// NewGame creates a new game on a board with the given number of dots on each side.
+func NewGame(boardSize int) *Game {
+	t := NewTopology(boardSize)
+	return &Game{
+		Topology:  t,
+		board:     NewBitBoard(t),
+		turn:      Player1Turn,
+		boxOwners: make(map[Box]Turn),
+	}
+}
+
+// Board returns the current board. The caller must not modify it; use Clone for simulations.
+func (g *Game) Board() Board { return g.board }
+
+// Turn returns the player to move.
+func (g *Game) Turn() Turn { return g.turn }
+
+// Player1Score returns the score of Player 1.
+func (g *Game) Player1Score() int { return g.player1Score }
+
+// Player2Score returns the score of Player 2.
+func (g *Game) Player2Score() int { return g.player2Score }
+
+// Score returns the score of the given player.
+func (g *Game) Score(t Turn) int {
+	if t == Player1Turn {
+		return g.player1Score
+	}
+	return g.player2Score
+}
+
+// BoxOwner returns the player who completed the box, or 0 if it is still open.
+func (g *Game) BoxOwner(b Box) Turn { return g.boxOwners[b] }
+
+// Records returns a copy of the move history.
+func (g *Game) Records() []MoveRecord { return append([]MoveRecord{}, g.records...) }
+
+// Step returns the number of edges drawn so far.
+func (g *Game) Step() int { return g.board.Size() }
+
+// Over checks if every edge has been drawn.
+func (g *Game) Over() bool { return g.board.Size() == g.EdgesCount() }
+
+// Winner returns the player with the higher score, or 0 on a draw.
+func (g *Game) Winner() Turn {
+	if g.player1Score > g.player2Score {
+		return Player1Turn
+	} else if g.player1Score < g.player2Score {
+		return Player2Turn
+	}
+	return 0
+}
+
+// Validate checks if the edge can be drawn in the current position.
+func (g *Game) Validate(e Edge) error {
+	if g.Over() {
+		return ErrGameOver
+	}
+	if e == InvalidEdge || !g.HasEdge(e) {
+		return ErrInvalidEdge
+	}
+	if g.board.Contains(e) {
+		return ErrEdgeDrawn
+	}
+	return nil
+}
+
+// Play draws the edge for the player to move and returns the boxes it completed.
+func (g *Game) Play(e Edge) ([]Box, error) {
+	d, err := g.PlayDelta(e)
+	return d.Boxes, err
+}
+
+// PlayDelta draws the edge for the player to move and returns the change it made to the game.
+func (g *Game) PlayDelta(e Edge) (Delta, error) {
+	if err := g.Validate(e); err != nil {
+		return Delta{}, err
+	}
+	g.records = append(g.records, MoveRecord{
+		TimeStamp:    time.Now(),
+		Step:         g.board.Size(),
+		Player:       g.turn,
+		MoveEdge:     e,
+		Move:         g.EdgeString(e),
+		Player1Score: g.player1Score,
+		Player2Score: g.player2Score,
+	})
+	obtainsBoxes := g.ObtainsBoxes(g.board, e)
+	for _, box := range obtainsBoxes {
+		g.boxOwners[box] = g.turn
+	}
+	if g.turn == Player1Turn {
+		g.player1Score += len(obtainsBoxes)
+	} else {
+		g.player2Score += len(obtainsBoxes)
+	}
+	d := Delta{Edge: e, Player: g.turn, Boxes: obtainsBoxes, TurnChanged: len(obtainsBoxes) == 0}
+	if d.TurnChanged {
+		ChangeTurn(&g.turn)
+	}
+	g.board.Add(e)
+	g.deltas = append(g.deltas, d)
+	return d, nil
+}
+
+// Replay resets the game and replays the move records, keeping them as the new move history.
+// The edge of a record is read from its notation if it has one, so records can be shared across board sizes.
+func (g *Game) Replay(records []MoveRecord) error {
+	g.reset()
+	records = append([]MoveRecord{}, records...)
+	for i, r := range records {
+		e, err := g.recordEdge(r)
+		if err != nil {
+			return err
+		}
+		records[i].MoveEdge = e
+		if _, err := g.Play(e); err != nil {
+			return err
+		}
+		records[i].Move = g.EdgeString(records[i].MoveEdge)
+	}
+	g.records = records
+	return nil
+}
+
+// recordEdge returns the edge of the move record, read from its notation when it has one.
+func (g *Game) recordEdge(r MoveRecord) (Edge, error) {
+	if r.Move == "" {
+		return r.MoveEdge, nil
+	}
+	return g.ParseEdge(r.Move)
+}
+
+// Undo reverts the last move in constant time and returns the change it had made.
+func (g *Game) Undo() (Delta, bool) {
+	if len(g.deltas) == 0 {
+		return Delta{}, false
+	}
+	d := g.deltas[len(g.deltas)-1]
+	g.deltas = g.deltas[:len(g.deltas)-1]
+	g.records = g.records[:len(g.records)-1]
+	g.board.Remove(d.Edge)
+	for _, box := range d.Boxes {
+		delete(g.boxOwners, box)
+	}
+	if d.Player == Player1Turn {
+		g.player1Score -= len(d.Boxes)
+	} else {
+		g.player2Score -= len(d.Boxes)
+	}
+	g.turn = d.Player
+	return d, true
+}
+
+// Clone creates an independent copy of the game sharing the same topology.
+func (g *Game) Clone() *Game {
+	cg := &Game{
+		Topology:     g.Topology,
+		board:        g.board.Clone(),
+		turn:         g.turn,
+		player1Score: g.player1Score,
+		player2Score: g.player2Score,
+		boxOwners:    make(map[Box]Turn, len(g.boxOwners)),
+		records:      g.Records(),
+		deltas:       append([]Delta{}, g.deltas...),
+	}
+	for b, t := range g.boxOwners {
+		cg.boxOwners[b] = t
+	}
+	return cg
+}
+
+// reset clears the board, scores and move history.
+func (g *Game) reset() {
+	g.board = NewBitBoard(g.Topology)
+	g.turn = Player1Turn
+	g.player1Score = 0
+	g.player2Score = 0
+	g.boxOwners = make(map[Box]Turn)
+	g.records = nil
+	g.deltas = nil
+}
@@ -0,0 +1,84 @@
+package engine
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+// sameGame reports how the game differs from the snapshot in board, turn, scores or box owners, or "" if it does not.
+func sameGame(g, snapshot *Game) string {
+	for _, e := range g.Edges {
+		if g.Board().Contains(e) != snapshot.Board().Contains(e) {
+			return "edge " + g.EdgeString(e)
+		}
+	}
+	if g.Step() != snapshot.Step() {
+		return "step"
+	}
+	if g.Turn() != snapshot.Turn() {
+		return "turn"
+	}
+	if g.Player1Score() != snapshot.Player1Score() || g.Player2Score() != snapshot.Player2Score() {
+		return "scores"
+	}
+	for _, b := range g.Boxes {
+		if g.BoxOwner(b) != snapshot.BoxOwner(b) {
+			return "owner of a box"
+		}
+	}
+	return ""
+}
+
+// TestUndo plays random games to the end, then undoes every move of the game and of its move tree,
+// checking each step against a clone taken before the move was played.
+func TestUndo(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	doubles, kept := 0, 0
+	for _, size := range []int{2, 3, 4, 5} {
+		for game := 0; game < 50; game++ {
+			g := NewGame(size)
+			tree := NewMoveTree(size)
+			var snapshots []*Game
+			var played []Edge
+			for _, idx := range r.Perm(g.EdgesCount()) {
+				snapshots, played = append(snapshots, g.Clone()), append(played, g.Edges[idx])
+				d, err := g.PlayDelta(g.Edges[idx])
+				if err != nil {
+					t.Fatal(err)
+				}
+				tree.Play(g.Records()[g.Step()-1])
+				if len(d.Boxes) == 2 {
+					doubles++
+				}
+				if !d.TurnChanged {
+					kept++
+				}
+			}
+			for i := len(snapshots) - 1; i >= 0; i-- {
+				d, ok := g.Undo()
+				if !ok || d.Edge != played[i] {
+					t.Fatalf("board of %v: undid %v (%v), expected move %v", size, g.EdgeString(d.Edge), ok, g.EdgeString(played[i]))
+				}
+				if diff := sameGame(g, snapshots[i]); diff != "" {
+					t.Fatalf("board of %v: undoing %v left the %v different from before the move", size, g.EdgeString(d.Edge), diff)
+				}
+				if !tree.Undo() || !slices.Equal(tree.Line(), g.Records()) {
+					t.Fatalf("board of %v: the move tree is at %v after undoing move %v", size, tree.Current, i+1)
+				}
+				if next, ok := tree.Next(); !ok || next.MoveEdge != d.Edge {
+					t.Fatalf("board of %v: the move tree redoes %v after undoing %v", size, next.Move, g.EdgeString(d.Edge))
+				}
+			}
+			if _, ok := g.Undo(); ok || tree.Undo() {
+				t.Fatal("undid a move at the start of the game")
+			}
+			if diff := sameGame(g, NewGame(size)); diff != "" {
+				t.Fatalf("board of %v: the %v differs from a new game", size, diff)
+			}
+		}
+	}
+	if doubles == 0 || kept == 0 {
+		t.Fatalf("the games had %v moves completing two boxes and %v moves keeping the turn", doubles, kept)
+	}
+}
@@ -0,0 +1,147 @@
+package engine
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GameLog is a finished game as saved next to the text game log, in a structured format that can be read back.
+type GameLog struct {
+	BoardSize    int          `json:"boardSize"`    // Number of dots on each side of the board
+	Records      []MoveRecord `json:"records"`      // Moves of the game, in order
+	Player1Score int          `json:"player1Score"` // Final score of Player 1
+	Player2Score int          `json:"player2Score"` // Final score of Player 2
+	Result       string       `json:"result"`       // Result of the game, like Player1 Win!
+	Position     string       `json:"position"`     // Notation of the final position
+}
+
+// NewGameLog returns the log of game g with the given result.
+func NewGameLog(g *Game, result string) GameLog {
+	return GameLog{
+		BoardSize:    g.BoardSize,
+		Records:      g.Records(),
+		Player1Score: g.Player1Score(),
+		Player2Score: g.Player2Score(),
+		Result:       result,
+		Position:     g.Position().String(),
+	}
+}
+
+// Game replays the records of the log and returns the game they reach.
+func (l GameLog) Game() (*Game, error) {
+	if l.BoardSize < 2 || l.BoardSize > MaxBoardSize {
+		return nil, fmt.Errorf("invalid board size %v", l.BoardSize)
+	}
+	g := NewGame(l.BoardSize)
+	if err := g.Replay(l.Records); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+var (
+	gameLogHeader = regexp.MustCompile(`^(\S+ \S+) BoardSize: (\d+)$`)
+	gameLogMove   = regexp.MustCompile(`^(\S+ \S+) Step: (\d+), Turn: (Player[12]), Edge: (.+), Player1Score: (\d+), Player2Score: (\d+)$`)
+	legacyEdge    = regexp.MustCompile(`^\((\d+), (\d+)\) => \((\d+), (\d+)\)$`)
+)
+
+// ParseGameLog parses a text game log: a line with the board size, a line per move and a line with the result.
+// The edges may be written in notation or in the (x, y) => (x, y) form of older logs.
+func ParseGameLog(r io.Reader) (GameLog, error) {
+	var l GameLog
+	var t *Topology
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		if t == nil {
+			m := gameLogHeader.FindStringSubmatch(text)
+			if m == nil {
+				return l, fmt.Errorf("line %v: expected the board size", line)
+			}
+			var err error
+			if l.BoardSize, err = strconv.Atoi(m[2]); err != nil {
+				return l, fmt.Errorf("line %v: %v", line, err)
+			}
+			if l.BoardSize < 2 || l.BoardSize > MaxBoardSize {
+				return l, fmt.Errorf("line %v: invalid board size %v", line, l.BoardSize)
+			}
+			t = NewTopology(l.BoardSize)
+			continue
+		}
+		m := gameLogMove.FindStringSubmatch(text)
+		if m == nil {
+			if _, result, ok := strings.Cut(text[min(len(text), len(time.DateTime)):], " "); ok {
+				l.Result = result
+			}
+			continue
+		}
+		timeStamp, err := time.ParseInLocation(time.DateTime, m[1], time.Local)
+		if err != nil {
+			return l, fmt.Errorf("line %v: %v", line, err)
+		}
+		e, err := parseLogEdge(t, m[4])
+		if err != nil {
+			return l, fmt.Errorf("line %v: %v", line, err)
+		}
+		var numbers [3]int
+		for i, s := range []string{m[2], m[5], m[6]} {
+			if numbers[i], err = strconv.Atoi(s); err != nil {
+				return l, fmt.Errorf("line %v: %v", line, err)
+			}
+		}
+		step, score1, score2 := numbers[0], numbers[1], numbers[2]
+		player := Player1Turn
+		if m[3] == Player2Turn.String() {
+			player = Player2Turn
+		}
+		l.Records = append(l.Records, MoveRecord{
+			TimeStamp:    timeStamp,
+			Step:         step,
+			Player:       player,
+			MoveEdge:     e,
+			Move:         t.EdgeString(e),
+			Player1Score: score1,
+			Player2Score: score2,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return l, err
+	}
+	if t == nil {
+		return l, fmt.Errorf("empty game log")
+	}
+	g, err := l.Game()
+	if err != nil {
+		return l, err
+	}
+	l.Player1Score, l.Player2Score, l.Position = g.Player1Score(), g.Player2Score(), g.Position().String()
+	return l, nil
+}
+
+// parseLogEdge parses an edge of a text game log, in notation or in the form of older logs.
+func parseLogEdge(t *Topology, s string) (Edge, error) {
+	m := legacyEdge.FindStringSubmatch(s)
+	if m == nil {
+		return t.ParseEdge(s)
+	}
+	var c [4]int
+	for i := range c {
+		var err error
+		if c[i], err = strconv.Atoi(m[i+1]); err != nil || c[i] >= t.BoardSize {
+			return InvalidEdge, fmt.Errorf("edge %q: %w", s, ErrInvalidEdge)
+		}
+	}
+	e := t.NewEdge(t.NewDot(c[0], c[1]), t.NewDot(c[2], c[3]))
+	if !t.HasEdge(e) {
+		return InvalidEdge, fmt.Errorf("edge %q: %w", s, ErrInvalidEdge)
+	}
+	return e, nil
+}
@@ -0,0 +1,79 @@
+package engine
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// gameLogText is the text log of a game on the board of 2 dots per side, with a line per edge.
+func gameLogText(header string, edges ...string) string {
+	lines := []string{"2024-06-01 12:00:00 " + header}
+	scores := []string{"0, Player2Score: 0", "0, Player2Score: 0", "0, Player2Score: 0", "0, Player2Score: 1"}
+	turns := []string{"Player1", "Player2", "Player1", "Player2"}
+	for i, e := range edges {
+		lines = append(lines, "2024-06-01 12:00:0"+string(rune('1'+i))+" Step: "+string(rune('1'+i))+", Turn: "+turns[i]+", Edge: "+e+", Player1Score: "+scores[i])
+	}
+	return strings.Join(append(lines, "2024-06-01 12:00:09 Player2 Win!"), "\n")
+}
+
+func TestParseGameLog(t *testing.T) {
+	notation := []string{"a1-b1", "a1-a2", "b1-b2", "a2-b2"}
+	legacy := []string{"(0, 0) => (1, 0)", "(0, 0) => (0, 1)", "(1, 0) => (1, 1)", "(0, 1) => (1, 1)"}
+	for name, edges := range map[string][]string{"notation": notation, "legacy": legacy} {
+		l, err := ParseGameLog(strings.NewReader(gameLogText("BoardSize: 2", edges...)))
+		if err != nil {
+			t.Fatalf("%v: %v", name, err)
+		}
+		if l.BoardSize != 2 || len(l.Records) != 4 || l.Result != "Player2 Win!" || l.Player2Score != 1 || l.Position != "2/2/0-1/f" {
+			t.Fatalf("%v: %+v", name, l)
+		}
+		for i, r := range l.Records {
+			if r.Move != notation[i] || r.Step != i+1 {
+				t.Fatalf("%v: record %v is %+v, expected %v", name, i, r, notation[i])
+			}
+		}
+
+		// The structured log reads back to the same game
+		j, err := json.Marshal(l)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var read GameLog
+		if err := json.Unmarshal(j, &read); err != nil {
+			t.Fatal(err)
+		}
+		g, err := read.Game()
+		if err != nil {
+			t.Fatalf("%v: %v", name, err)
+		}
+		if g.Position().String() != l.Position {
+			t.Fatalf("%v: JSON log reaches %v, expected %v", name, g.Position(), l.Position)
+		}
+	}
+}
+
+func TestParseGameLogRejects(t *testing.T) {
+	for name, text := range map[string]string{
+		"empty":                  "",
+		"missing header":         strings.Join(strings.Split(gameLogText("BoardSize: 2", "a1-b1"), "\n")[1:], "\n"),
+		"board size 1":           gameLogText("BoardSize: 1", "a1-b1"),
+		"board size too large":   gameLogText("BoardSize: 21", "a1-b1"),
+		"board size overflowing": gameLogText("BoardSize: 99999999999999999999", "a1-b1"),
+		"negative board size":    gameLogText("BoardSize: -3", "a1-b1"),
+		"edge off the board":     gameLogText("BoardSize: 2", "a1-c1"),
+		"legacy edge off board":  gameLogText("BoardSize: 2", "(0, 0) => (2, 0)"),
+		"legacy edge overflow":   gameLogText("BoardSize: 2", "(0, 0) => (99999999999999999999, 0)"),
+		"edge drawn twice":       gameLogText("BoardSize: 2", "a1-b1", "a1-b1"),
+		"overflowing step":       strings.Replace(gameLogText("BoardSize: 2", "a1-b1"), "Step: 1", "Step: 99999999999999999999", 1),
+	} {
+		if l, err := ParseGameLog(strings.NewReader(text)); err == nil {
+			t.Errorf("%v: parsed %+v", name, l)
+		}
+	}
+	for _, size := range []int{0, 1, MaxBoardSize + 1} {
+		if _, err := (GameLog{BoardSize: size}).Game(); err == nil {
+			t.Errorf("replayed a log of board size %v", size)
+		}
+	}
+}
@@ -0,0 +1,411 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"sort"
+	"sync"
+)
+
+// A lobby hosts many games of the network game protocol at once, each with its own Game, so that a server can run
+// the games of a tournament. A client connected to the lobby sends list to get the games, create to open a game,
+// join to take the free seat of an open game, watch to spectate a game and leave to go back to the lobby. A hello
+// asks for a quick match, which joins the oldest open quick match of the board size or opens a new one, so that the
+// network game of the app can join a lobby like it joins a host. Taking a seat or watching a game is answered with
+// a welcome, then the state of the game every time it changes. The moves of a game are played once both seats are
+// taken, the other seat of a game created with an engine being played by that strategy. A game leaves the lobby once
+// it is over, and closes when one of its players leaves: its other clients get an error and go back to the lobby.
+
+// Limits of the lobby
+const (
+	LobbyBoardSize    = 6  // Number of dots on each side of the board of a quick match that gives none
+	LobbyMinBoardSize = 2  // Minimum board size of the games of a lobby, the maximum being MaxBoardSize
+	LobbyClientBuffer = 64 // Messages kept for a slow client before it is disconnected
+)
+
+// NetGameInfo describes a game of a lobby.
+type NetGameInfo struct {
+	ID         int    `json:"id"`               // ID of the game in the lobby
+	BoardSize  int    `json:"boardSize"`        // Number of dots on each side of the board
+	Players    int    `json:"players"`          // Number of seats taken by clients
+	Engine     string `json:"engine,omitempty"` // Strategy of the engine seat, if any
+	Spectators int    `json:"spectators"`       // Number of clients spectating the game
+	Step       int    `json:"step"`             // Number of moves played
+	Open       bool   `json:"open"`             // Whether a seat is free
+}
+
+// Lobby serves the games of many clients over the network game protocol. It is safe for concurrent use.
+type Lobby struct {
+	Settings  Settings    // Search settings of the engine seats
+	BoardSize int         // Board size of the quick matches that give none
+	Log       *log.Logger // Logger of the games opened and closed, nil to log nothing
+
+	lock   sync.Mutex         // Mutex for games and clients synchronization
+	games  map[int]*lobbyGame // Games by ID
+	nextID int                // ID of the last game opened
+}
+
+// lobbyGame is a game of a lobby with its clients. It is guarded by the lock of the lobby.
+type lobbyGame struct {
+	id         int                   // ID of the game
+	game       *Game                 // State of the game
+	quick      bool                  // Whether it was opened by a quick match
+	seats      map[Turn]*lobbyClient // Client playing each seat taken by a client
+	spectators map[*lobbyClient]bool // Clients spectating the game
+	engine     Strategy              // Strategy playing engineSeat, nil if none
+	engineName string                // Name of the strategy
+	engineSeat Turn                  // Seat of the engine
+	cancel     context.CancelFunc    // Stops the running search of the engine, nil if none is running
+	closed     bool                  // Whether the game was closed
+}
+
+// lobbyClient is a client connected to a lobby. Its fields are guarded by the lock of the lobby.
+type lobbyClient struct {
+	conn   *NetConn        // Connection of the client
+	out    chan NetMessage // Messages waiting to be sent
+	game   *lobbyGame      // Game played or spectated, nil in the lobby
+	seat   Turn            // Seat in the game, none for a spectator
+	closed bool            // Whether out was closed
+}
+
+// NewLobby creates an empty lobby whose engines search with the given settings.
+func NewLobby(s Settings) *Lobby {
+	s.Normalize()
+	return &Lobby{Settings: s, BoardSize: LobbyBoardSize, games: make(map[int]*lobbyGame)}
+}
+
+// Serve serves the clients connecting to the listener until it fails.
+func (l *Lobby) Serve(listener net.Listener) error {
+	for {
+		c, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		go l.ServeConn(c)
+	}
+}
+
+// ServeConn serves a client until it disconnects, then closes rwc. In-process clients can be served over net.Pipe.
+func (l *Lobby) ServeConn(rwc io.ReadWriteCloser) {
+	c := &lobbyClient{conn: NewNetConn(rwc), out: make(chan NetMessage, LobbyClientBuffer)}
+	go func() {
+		for m := range c.out {
+			if err := c.conn.Send(m); err != nil {
+				_ = c.conn.Close()
+			}
+		}
+	}()
+	defer func() {
+		l.lock.Lock()
+		defer l.lock.Unlock()
+		l.leave(c, "disconnected")
+		c.closed = true
+		close(c.out)
+	}()
+	for {
+		m, err := c.conn.Receive()
+		if err != nil {
+			if m.Type != "" {
+				_ = c.conn.Send(NetMessage{Type: NetError, Error: err.Error()}) // Version mismatch
+			}
+			_ = c.conn.Close()
+			return
+		}
+		l.lock.Lock()
+		if err := l.handle(c, m); err != nil {
+			c.send(NetMessage{Type: NetError, Game: m.Game, Error: err.Error()})
+		}
+		l.lock.Unlock()
+	}
+}
+
+// Games returns the games of the lobby by ID, of the board size unless it is 0.
+func (l *Lobby) Games(boardSize int) []NetGameInfo {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.list(boardSize)
+}
+
+// send queues a message for the client, which is disconnected if too many are waiting.
+func (c *lobbyClient) send(m NetMessage) {
+	if c.closed {
+		return
+	}
+	select {
+	case c.out <- m:
+	default:
+		_ = c.conn.Close()
+	}
+}
+
+// logf logs a message if the lobby has a logger.
+func (l *Lobby) logf(format string, a ...any) {
+	if l.Log != nil {
+		l.Log.Printf(format, a...)
+	}
+}
+
+// handle runs a message of the client. It must be called with the lock held.
+func (l *Lobby) handle(c *lobbyClient, m NetMessage) error {
+	switch m.Type {
+	case NetList:
+		c.send(NetMessage{Type: NetLobby, Games: l.list(m.BoardSize)})
+		return nil
+	case NetLeave:
+		l.leave(c, "left")
+		c.send(NetMessage{Type: NetLobby, Games: l.list(0)})
+		return nil
+	case NetMove:
+		return l.move(c, m.Move)
+	}
+	if c.game != nil {
+		return fmt.Errorf("already in game %v, leave it first", c.game.id)
+	}
+	switch m.Type {
+	case NetHello:
+		return l.quickMatch(c, m.BoardSize)
+	case NetCreate:
+		g, err := l.open(m.BoardSize, m.Engine, false)
+		if err != nil {
+			return err
+		}
+		l.seat(c, g, Player1Turn)
+		return nil
+	case NetJoin:
+		g, ok := l.games[m.Game]
+		if !ok {
+			return fmt.Errorf("no game %v", m.Game)
+		}
+		seat, ok := g.freeSeat()
+		if !ok {
+			return fmt.Errorf("game %v is full", g.id)
+		}
+		l.seat(c, g, seat)
+		return nil
+	case NetWatch:
+		g, ok := l.games[m.Game]
+		if !ok {
+			return fmt.Errorf("no game %v", m.Game)
+		}
+		g.spectators[c] = true
+		c.game, c.seat = g, 0
+		c.send(NetMessage{Type: NetWelcome, Game: g.id})
+		c.send(g.state())
+		return nil
+	default:
+		return fmt.Errorf("unexpected %v", m.Type)
+	}
+}
+
+// list returns the games of the board size unless it is 0. It must be called with the lock held.
+func (l *Lobby) list(boardSize int) []NetGameInfo {
+	games := make([]NetGameInfo, 0, len(l.games))
+	for _, g := range l.games {
+		if boardSize == 0 || g.game.BoardSize == boardSize {
+			games = append(games, g.info())
+		}
+	}
+	sort.Slice(games, func(i, j int) bool { return games[i].ID < games[j].ID })
+	return games
+}
+
+// quickMatch seats the client in the oldest open quick match of the board size, or in a new one.
+// It must be called with the lock held.
+func (l *Lobby) quickMatch(c *lobbyClient, boardSize int) error {
+	if boardSize == 0 {
+		boardSize = l.BoardSize
+	}
+	var match *lobbyGame
+	for _, g := range l.games {
+		if _, open := g.freeSeat(); open && g.quick && g.game.BoardSize == boardSize && (match == nil || g.id < match.id) {
+			match = g
+		}
+	}
+	if match == nil {
+		var err error
+		if match, err = l.open(boardSize, "", true); err != nil {
+			return err
+		}
+	}
+	seat, _ := match.freeSeat()
+	l.seat(c, match, seat)
+	return nil
+}
+
+// open opens a game of the board size, against the engine if any. It must be called with the lock held.
+func (l *Lobby) open(boardSize int, engine string, quick bool) (*lobbyGame, error) {
+	if boardSize < LobbyMinBoardSize || boardSize > MaxBoardSize {
+		return nil, fmt.Errorf("board size %v out of %v to %v", boardSize, LobbyMinBoardSize, MaxBoardSize)
+	}
+	l.nextID++
+	g := &lobbyGame{
+		id:         l.nextID,
+		game:       NewGame(boardSize),
+		quick:      quick,
+		seats:      make(map[Turn]*lobbyClient),
+		spectators: make(map[*lobbyClient]bool),
+	}
+	if engine != "" {
+		st, err := NewStrategy(engine)
+		if err != nil {
+			return nil, err
+		}
+		g.engine, g.engineName, g.engineSeat = st, engine, Player2Turn
+	}
+	l.games[g.id] = g
+	l.logf("game %v opened: board size %v, engine %q", g.id, boardSize, engine)
+	return g, nil
+}
+
+// seat gives the seat of the game to the client, and starts the game once both seats are taken.
+// It must be called with the lock held.
+func (l *Lobby) seat(c *lobbyClient, g *lobbyGame, seat Turn) {
+	g.seats[seat] = c
+	c.game, c.seat = g, seat
+	c.send(NetMessage{Type: NetWelcome, Seat: seat, Game: g.id})
+	g.broadcast()
+	l.play(g)
+}
+
+// move plays the edge for the client, if it plays the seat to move and the edge is free.
+// It must be called with the lock held.
+func (l *Lobby) move(c *lobbyClient, move string) error {
+	g := c.game
+	if g == nil || c.seat == 0 {
+		return errors.New("not playing a game")
+	}
+	if _, open := g.freeSeat(); open {
+		return errors.New("waiting for an opponent")
+	}
+	if g.game.Turn() != c.seat {
+		return fmt.Errorf("it is the turn of %v", g.game.Turn())
+	}
+	e, err := g.game.ParseEdge(move)
+	if err != nil {
+		return err
+	}
+	if _, err := g.game.Play(e); err != nil {
+		return err
+	}
+	g.broadcast()
+	l.play(g)
+	return nil
+}
+
+// play takes a game that is over out of the lobby, its clients keeping its final state until they leave, or starts
+// the search of the engine if it is to move. It is called after every change of the game, with the lock held.
+func (l *Lobby) play(g *lobbyGame) {
+	if g.game.Over() && l.games[g.id] == g {
+		delete(l.games, g.id)
+		l.logf("game %v over: %v-%v", g.id, g.game.Player1Score(), g.game.Player2Score())
+		return
+	}
+	if g.engine == nil || g.cancel != nil || g.closed || g.game.Over() || g.game.Turn() != g.engineSeat {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	g.cancel = cancel
+	p, s := g.game.Position(), l.Settings
+	go func() {
+		e := g.engine.BestEdge(ctx, p, s)
+		l.lock.Lock()
+		defer l.lock.Unlock()
+		cancel()
+		if g.closed {
+			return
+		}
+		g.cancel = nil
+		if _, err := g.game.Play(e); err != nil {
+			l.logf("game %v: engine %v played %v: %v", g.id, g.engineName, g.game.EdgeString(e), err)
+			l.close(g, fmt.Sprintf("engine %v failed", g.engineName))
+			return
+		}
+		g.broadcast()
+		l.play(g)
+	}()
+}
+
+// leave takes the client out of its game. A player leaving closes the game. It must be called with the lock held.
+func (l *Lobby) leave(c *lobbyClient, reason string) {
+	g := c.game
+	if g == nil {
+		return
+	}
+	c.game = nil
+	if c.seat == 0 {
+		delete(g.spectators, c)
+		return
+	}
+	delete(g.seats, c.seat)
+	l.close(g, fmt.Sprintf("%v %v", c.seat, reason))
+}
+
+// close closes the game, and sends its clients back to the lobby. It must be called with the lock held.
+func (l *Lobby) close(g *lobbyGame, reason string) {
+	if g.closed {
+		return
+	}
+	g.closed = true
+	if g.cancel != nil {
+		g.cancel()
+	}
+	delete(l.games, g.id)
+	for _, c := range g.clients() {
+		c.game = nil
+		c.send(NetMessage{Type: NetError, Game: g.id, Error: fmt.Sprintf("game %v closed: %v", g.id, reason)})
+	}
+	l.logf("game %v closed: %v", g.id, reason)
+}
+
+// freeSeat returns the seat free in the game, if any.
+func (g *lobbyGame) freeSeat() (Turn, bool) {
+	for _, seat := range []Turn{Player1Turn, Player2Turn} {
+		if _, ok := g.seats[seat]; !ok && (g.engine == nil || seat != g.engineSeat) {
+			return seat, true
+		}
+	}
+	return 0, false
+}
+
+// clients returns the players and the spectators of the game.
+func (g *lobbyGame) clients() []*lobbyClient {
+	clients := make([]*lobbyClient, 0, len(g.seats)+len(g.spectators))
+	for _, c := range g.seats {
+		clients = append(clients, c)
+	}
+	for c := range g.spectators {
+		clients = append(clients, c)
+	}
+	return clients
+}
+
+// state returns the state message of the game.
+func (g *lobbyGame) state() NetMessage {
+	return NetMessage{Type: NetState, Game: g.id, BoardSize: g.game.BoardSize, Records: g.game.Records()}
+}
+
+// broadcast sends the state of the game to its players and spectators.
+func (g *lobbyGame) broadcast() {
+	m := g.state()
+	for _, c := range g.clients() {
+		c.send(m)
+	}
+}
+
+// info describes the game.
+func (g *lobbyGame) info() NetGameInfo {
+	_, open := g.freeSeat()
+	return NetGameInfo{
+		ID:         g.id,
+		BoardSize:  g.game.BoardSize,
+		Players:    len(g.seats),
+		Engine:     g.engineName,
+		Spectators: len(g.spectators),
+		Step:       g.game.Step(),
+		Open:       open,
+	}
+}
@@ -0,0 +1,158 @@
+package engine
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+// lobbyTestTimeout bounds the wait for a message of the lobby, so that a missing message fails the test.
+const lobbyTestTimeout = 5 * time.Second
+
+// lobbyTestClient is an in-process client of a lobby.
+type lobbyTestClient struct {
+	t    *testing.T
+	pipe net.Conn
+	*NetConn
+}
+
+// newLobbyTestClient connects a client to the lobby over net.Pipe.
+func newLobbyTestClient(t *testing.T, l *Lobby) *lobbyTestClient {
+	client, server := net.Pipe()
+	go l.ServeConn(server)
+	t.Cleanup(func() { _ = client.Close() })
+	return &lobbyTestClient{t: t, pipe: client, NetConn: NewNetConn(client)}
+}
+
+// send sends a message to the lobby.
+func (c *lobbyTestClient) send(m NetMessage) {
+	c.t.Helper()
+	if err := c.Send(m); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+// receive waits for the next message of the lobby and checks its type.
+func (c *lobbyTestClient) receive(messageType string) NetMessage {
+	c.t.Helper()
+	_ = c.pipe.SetReadDeadline(time.Now().Add(lobbyTestTimeout))
+	m, err := c.Receive()
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	if m.Type != messageType {
+		c.t.Fatalf("got %v %q, expected %v", m.Type, m.Error, messageType)
+	}
+	return m
+}
+
+// welcome waits for the welcome to the game and the state that follows it, and checks the seat.
+func (c *lobbyTestClient) welcome(seat Turn) (game int) {
+	c.t.Helper()
+	m := c.receive(NetWelcome)
+	if m.Seat != seat {
+		c.t.Fatalf("seated as %v, expected %v", m.Seat, seat)
+	}
+	c.receive(NetState)
+	return m.Game
+}
+
+func TestLobbyQuickMatch(t *testing.T) {
+	l := NewLobby(Settings{})
+	a, b, c := newLobbyTestClient(t, l), newLobbyTestClient(t, l), newLobbyTestClient(t, l)
+
+	a.send(NetMessage{Type: NetHello, BoardSize: 3})
+	game := a.welcome(Player1Turn)
+	c.send(NetMessage{Type: NetHello, BoardSize: 4})
+	if c.welcome(Player1Turn) == game {
+		t.Fatal("quick match paired board sizes 3 and 4")
+	}
+	b.send(NetMessage{Type: NetHello, BoardSize: 3})
+	if b.welcome(Player2Turn) != game {
+		t.Fatal("quick match opened a new game instead of joining the open one")
+	}
+	a.receive(NetState) // The opponent joined
+
+	c.send(NetMessage{Type: NetList, BoardSize: 3})
+	games := c.receive(NetLobby).Games
+	if len(games) != 1 || games[0].ID != game || games[0].Open || games[0].Players != 2 {
+		t.Fatalf("games of board size 3: %+v", games)
+	}
+}
+
+func TestLobbyJoinAndWatch(t *testing.T) {
+	l := NewLobby(Settings{})
+	a, b, w1, w2 := newLobbyTestClient(t, l), newLobbyTestClient(t, l), newLobbyTestClient(t, l), newLobbyTestClient(t, l)
+
+	a.send(NetMessage{Type: NetCreate, BoardSize: 3})
+	game := a.welcome(Player1Turn)
+	a.send(NetMessage{Type: NetMove, Move: "a1-b1"})
+	if m := a.receive(NetError); m.Error != "waiting for an opponent" {
+		t.Fatalf("move before the opponent joined: %v", m.Error)
+	}
+	b.send(NetMessage{Type: NetJoin, Game: game})
+	b.welcome(Player2Turn)
+	a.receive(NetState)
+	for _, w := range []*lobbyTestClient{w1, w2} {
+		w.send(NetMessage{Type: NetWatch, Game: game})
+		w.welcome(0)
+	}
+	w1.send(NetMessage{Type: NetMove, Move: "a1-b1"})
+	w1.receive(NetError) // Spectators do not play
+
+	b.send(NetMessage{Type: NetMove, Move: "a1-b1"})
+	if m := b.receive(NetError); m.Error != "it is the turn of Player1" {
+		t.Fatalf("move out of turn: %v", m.Error)
+	}
+	a.send(NetMessage{Type: NetMove, Move: "a1-c1"})
+	a.receive(NetError) // Not an edge
+	a.send(NetMessage{Type: NetMove, Move: "a1-b1"})
+	for _, c := range []*lobbyTestClient{a, b, w1, w2} {
+		if m := c.receive(NetState); len(m.Records) != 1 || m.Records[0].Move != "a1-b1" {
+			t.Fatalf("state after the move: %+v", m.Records)
+		}
+	}
+	b.send(NetMessage{Type: NetMove, Move: "a1-b1"})
+	b.receive(NetError) // Already drawn
+
+	if games := l.Games(0); len(games) != 1 || games[0].Spectators != 2 {
+		t.Fatalf("games: %+v", games)
+	}
+	a.send(NetMessage{Type: NetLeave})
+	a.receive(NetLobby)
+	for _, c := range []*lobbyTestClient{b, w1, w2} {
+		c.receive(NetError) // The game closed
+	}
+	if games := l.Games(0); len(games) != 0 {
+		t.Fatalf("games after a player left: %+v", games)
+	}
+}
+
+func TestLobbyEngineSeat(t *testing.T) {
+	l := NewLobby(Settings{Goroutines: 1, SearchTime: 10 * time.Millisecond})
+	a := newLobbyTestClient(t, l)
+
+	a.send(NetMessage{Type: NetCreate, BoardSize: 3, Engine: "Unknown"})
+	a.receive(NetError)
+	a.send(NetMessage{Type: NetCreate, BoardSize: 3, Engine: GreedyStrategy})
+	a.welcome(Player1Turn)
+	g := NewGame(3)
+	for !g.Over() {
+		if g.Turn() == Player1Turn {
+			for _, e := range g.Edges {
+				if !g.Board().Contains(e) {
+					a.send(NetMessage{Type: NetMove, Move: g.EdgeString(e)})
+					break
+				}
+			}
+		}
+		m := a.receive(NetState)
+		g = NewGame(3)
+		if err := g.Replay(m.Records); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if games := l.Games(0); len(games) != 0 {
+		t.Fatalf("games after the game is over: %+v", games)
+	}
+}
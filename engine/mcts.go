@@ -0,0 +1,325 @@
+package engine
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// DefaultExploration is the default UCT exploration constant, tuned for margins normalized by the number of boxes.
+const DefaultExploration = 0.5
+
+// MCTSMaxNodes is the number of nodes kept by the search graphs of all the workers together, which bounds the memory
+// of a long search or of pondering while the opponent thinks. A worker stops growing its graph once its share is full.
+const MCTSMaxNodes = 1 << 20
+
+// mctsNode is a node of the search graph. It represents a position, shared by every move order reaching it.
+type mctsNode struct {
+	size     int        // Number of edges drawn in the position
+	untried  []Edge     // Candidate moves not expanded yet, the next one last; nil until the first expansion
+	children []mctsEdge // Expanded moves
+	full     bool       // Whether every candidate move has been expanded
+	visits   int        // Number of simulations through this node
+	sumScore float64    // Sum of margins from the point of view of the player to move
+	epoch    int        // Epoch of the last reroot that reached the node from the root
+}
+
+// mctsEdge is a move of the search graph.
+type mctsEdge struct {
+	edge     Edge      // Edge drawn by the move
+	captured int       // Number of boxes completed by edge
+	child    *mctsNode // Position reached by the move
+	visits   int       // Number of simulations through this move
+}
+
+// value returns the average margin of the move from the point of view of the player who draws it.
+func (e *mctsEdge) value() float64 {
+	if e.child.visits == 0 {
+		return float64(e.captured)
+	}
+	v := e.child.sumScore / float64(e.child.visits)
+	if e.captured > 0 {
+		return float64(e.captured) + v // The player who drew the edge moves again
+	}
+	return -v
+}
+
+// MCTS is a Monte Carlo Tree Search engine using UCT selection.
+// Every worker grows its own search graph and the statistics of the root moves are merged at the end
+// (root parallelization), so workers never wait for each other.
+// Positions reached by different move orders share one node, and the graphs are kept between searches
+// so that the statistics of the position actually reached are reused.
+// An MCTS can be shared between goroutines, but only one search runs at a time.
+type MCTS struct {
+	Exploration float64 // UCT exploration constant
+
+	searchLock sync.Mutex  // Serializes searches
+	trees      []*mctsTree // Search graph of each worker
+	solver     Solver      // Endgame solver, used by BestEdge once few edges are left
+}
+
+// mctsTree is the search graph of one worker.
+type mctsTree struct {
+	t         *Topology            // Topology of the nodes
+	rootBoard Board                // Board at the root of the search
+	root      *mctsNode            // Root of the search
+	nodes     map[string]*mctsNode // Nodes indexed by the key of their board
+	epoch     int                  // Number of reroots, marking the nodes still reachable
+}
+
+// NewMCTS creates a new tree search engine with the given exploration constant.
+func NewMCTS(exploration float64) *MCTS { return &MCTS{Exploration: exploration} }
+
+// boardKey returns a string identifying the set of edges drawn on board b.
+func boardKey(t *Topology, b Board) string {
+	if bb, ok := b.(*BitBoard); ok && bb.t == t {
+		return bb.key()
+	}
+	key := make([]byte, (t.EdgesCount()+7)/8)
+	for i, e := range t.Edges {
+		if b.Contains(e) {
+			key[i/8] |= 1 << (i % 8)
+		}
+	}
+	return string(key)
+}
+
+// node returns the node of board b, creating it if needed.
+func (tr *mctsTree) node(b Board) *mctsNode {
+	key := boardKey(tr.t, b)
+	n, ok := tr.nodes[key]
+	if !ok {
+		n = &mctsNode{size: b.Size()}
+		tr.nodes[key] = n
+	}
+	return n
+}
+
+// reroot moves the root of the search to board b, reusing its node if it was already explored,
+// and drops the nodes that can no longer be reached.
+func (tr *mctsTree) reroot(t *Topology, b Board) {
+	if tr.t != t {
+		tr.t = t
+		tr.nodes = make(map[string]*mctsNode)
+	}
+	tr.rootBoard = b.Clone()
+	tr.root = tr.node(b)
+	tr.epoch++
+	tr.root.epoch = tr.epoch
+	for stack := []*mctsNode{tr.root}; len(stack) > 0; {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for i := range n.children {
+			if c := n.children[i].child; c.epoch != tr.epoch {
+				c.epoch = tr.epoch
+				stack = append(stack, c)
+			}
+		}
+	}
+	for key, n := range tr.nodes {
+		if n.epoch != tr.epoch {
+			delete(tr.nodes, key)
+		}
+	}
+}
+
+// Search runs the tree search on board b with the given number of workers for the given time,
+// and returns the most visited edge of the root. The search stops early when ctx is done.
+func (m *MCTS) Search(ctx context.Context, t *Topology, b Board, goroutines int, searchTime time.Duration) Edge {
+	return m.search(ctx, t, b, goroutines, searchTime, m.Exploration)
+}
+
+// search runs the tree search with the given exploration constant.
+func (m *MCTS) search(ctx context.Context, t *Topology, b Board, goroutines int, searchTime time.Duration, exploration float64) (bestEdge Edge) {
+	if b.Size() >= t.EdgesCount() {
+		return InvalidEdge
+	}
+	visits, _ := m.searchStats(ctx, t, b, goroutines, searchTime, exploration)
+
+	// Choose the most visited edge of the root over all workers
+	bestVisits := -1
+	for _, e := range t.Edges {
+		if v, ok := visits[e]; ok && v > bestVisits {
+			bestEdge, bestVisits = e, v
+		}
+	}
+	// Fall back to the rollout policy if the tree has not been expanded in time
+	if bestEdge == InvalidEdge {
+		bestEdge = getNextEdges(t, b, rand.New(rand.NewSource(time.Now().UnixNano())))
+	}
+	return
+}
+
+// searchStats runs the tree search and returns the number of simulations through each move of the root over all workers,
+// and the sum of their margins of the remaining boxes from the point of view of the player to move.
+func (m *MCTS) searchStats(ctx context.Context, t *Topology, b Board, goroutines int, searchTime time.Duration, exploration float64) (visits map[Edge]int, sumScores map[Edge]float64) {
+	m.searchLock.Lock()
+	defer m.searchLock.Unlock()
+	ctx, cancel := context.WithTimeout(ctx, searchTime)
+	defer cancel()
+	m.grow(ctx, t, b, goroutines, exploration)
+
+	visits = make(map[Edge]int)
+	sumScores = make(map[Edge]float64)
+	for _, tr := range m.trees {
+		for i := range tr.root.children {
+			c := &tr.root.children[i]
+			visits[c.edge] += c.visits
+			sumScores[c.edge] += c.value() * float64(c.visits)
+		}
+	}
+	return
+}
+
+// grow moves the root of the search graphs to board b and grows them with the given number of workers until ctx is done
+// or the graphs hold MCTSMaxNodes nodes. Every iteration counts as a node of WithNodeLimit. It must be called with
+// searchLock held.
+func (m *MCTS) grow(ctx context.Context, t *Topology, b Board, goroutines int, exploration float64) {
+	counter := nodeCounterFrom(ctx)
+	for len(m.trees) < goroutines {
+		m.trees = append(m.trees, &mctsTree{})
+	}
+	m.trees = m.trees[:goroutines]
+	maxNodes := max(MCTSMaxNodes/goroutines, 1)
+
+	var wg sync.WaitGroup
+	wg.Add(goroutines)
+	for i, tr := range m.trees {
+		r := rand.New(rand.NewSource(time.Now().UnixNano() + int64(i)))
+		go func() {
+			defer wg.Done()
+			tr.reroot(t, b)
+			var cb Board
+			var path []*mctsEdge
+			for ctx.Err() == nil && len(tr.nodes) < maxNodes {
+				cb = resetBoard(cb, tr.rootBoard)
+				path = tr.iterate(cb, r, exploration, path[:0])
+				counter.add()
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// iterate runs one selection, expansion, simulation and backpropagation step on cb, a copy of the root board.
+// path is a scratch buffer owned by the calling worker; it is returned for reuse.
+func (tr *mctsTree) iterate(cb Board, r *rand.Rand, exploration float64, path []*mctsEdge) []*mctsEdge {
+	t := tr.t
+	boxes := float64(len(t.Boxes))
+
+	// Selection and expansion
+	node := tr.root
+	for cb.Size() < t.EdgesCount() {
+		if !node.full {
+			move := tr.expand(node, cb, r)
+			path = append(path, move)
+			node = move.child
+			break
+		}
+		var best *mctsEdge
+		bestScore := math.Inf(-1)
+		logVisits := math.Log(float64(node.visits))
+		for i := range node.children {
+			c := &node.children[i]
+			score := c.value()/boxes + exploration*math.Sqrt(logVisits/float64(c.visits))
+			if score > bestScore {
+				best, bestScore = c, score
+			}
+		}
+		path = append(path, best)
+		cb.Add(best.edge)
+		node = best.child
+	}
+
+	// Simulation
+	_, score := Rollout(t, cb, r)
+
+	// Backpropagation
+	v := float64(score)
+	node.visits++
+	node.sumScore += v
+	for i := len(path) - 1; i >= 0; i-- {
+		path[i].visits++
+		if path[i].captured > 0 {
+			v = float64(path[i].captured) + v // The player who drew the edge moves again
+		} else {
+			v = -v
+		}
+		parent := tr.root
+		if i > 0 {
+			parent = path[i-1].child
+		}
+		parent.visits++
+		parent.sumScore += v
+	}
+	return path
+}
+
+// expand adds the next candidate move of node, whose position is cb, and draws it on cb.
+func (tr *mctsTree) expand(node *mctsNode, cb Board, r *rand.Rand) *mctsEdge {
+	t := tr.t
+	if node.untried == nil {
+		node.untried = candidateEdges(t, cb, r)
+	}
+	e := node.untried[len(node.untried)-1]
+	node.untried = node.untried[:len(node.untried)-1]
+	node.full = len(node.untried) == 0
+	captured := t.ObtainsScore(cb, e)
+	cb.Add(e)
+	node.children = append(node.children, mctsEdge{edge: e, captured: captured, child: tr.node(cb)})
+	return &node.children[len(node.children)-1]
+}
+
+// candidateEdges returns the moves worth searching on board b, in reverse order of expansion.
+// Captures are expanded first, and edges giving a box away are only considered once no safe edge is left,
+// so that the tree follows the same knowledge as the rollout policy.
+func candidateEdges(t *Topology, b Board, r *rand.Rand) []Edge {
+	var captures, safes, others []Edge
+	for _, e := range t.Edges {
+		if b.Contains(e) {
+			continue
+		}
+		switch edgeClass(t, b, e) {
+		case captureEdge:
+			captures = append(captures, e)
+		case safeEdge:
+			safes = append(safes, e)
+		default:
+			others = append(others, e)
+		}
+	}
+	if len(safes) > 0 {
+		others = nil
+	}
+	candidates := make([]Edge, 0, len(captures)+len(safes)+len(others))
+	for _, group := range [][]Edge{others, safes, captures} {
+		r.Shuffle(len(group), func(i, j int) { group[i], group[j] = group[j], group[i] })
+		candidates = append(candidates, group...)
+	}
+	return candidates
+}
+
+const (
+	captureEdge = iota // Edge completing at least one box
+	safeEdge           // Edge giving no box to the opponent
+	unsafeEdge         // Edge drawing the third side of a box
+)
+
+// edgeClass classifies a free edge of board b.
+func edgeClass(t *Topology, b Board, e Edge) int {
+	if bb, ok := b.(*BitBoard); ok && bb.t == t {
+		return bb.edgeClass(t.EdgeIndex(e))
+	}
+	class := safeEdge
+	for _, box := range t.AdjacentBoxes(e) {
+		switch t.EdgesCountInBox(b, box) {
+		case 3:
+			return captureEdge
+		case 2:
+			class = unsafeEdge
+		}
+	}
+	return class
+}
@@ -0,0 +1,97 @@
+package engine
+
+// MoveNode is a move of a move tree with the variations that follow it.
+type MoveNode struct {
+	Record   MoveRecord  `json:"record"`             // Move leading to the node, unset for the root
+	Children []*MoveNode `json:"children,omitempty"` // Moves played after the move, the first one being the main line
+	Selected int         `json:"selected"`           // Index of the child followed by redo, the last one visited
+}
+
+// MoveTree is the history of a game with every variation tried, so that undone moves can be redone
+// and a different move starts a new variation instead of discarding the old line.
+type MoveTree struct {
+	BoardSize int       `json:"boardSize"` // Number of dots on each side of the board
+	Root      *MoveNode `json:"root"`      // Start of the game
+	Current   []int     `json:"current"`   // Indexes of the children leading from the root to the current node
+}
+
+// NewMoveTree creates an empty move tree for a board with the given number of dots on each side.
+func NewMoveTree(boardSize int) *MoveTree {
+	return &MoveTree{BoardSize: boardSize, Root: &MoveNode{}}
+}
+
+// Node returns the node reached by following the children of the path from the root, or nil if there is none.
+func (t *MoveTree) Node(path []int) *MoveNode {
+	n := t.Root
+	for _, i := range path {
+		if n == nil || i < 0 || i >= len(n.Children) {
+			return nil
+		}
+		n = n.Children[i]
+	}
+	return n
+}
+
+// Records returns the moves leading from the root to the node of the path.
+func (t *MoveTree) Records(path []int) []MoveRecord {
+	var records []MoveRecord
+	n := t.Root
+	for _, i := range path {
+		if i < 0 || i >= len(n.Children) {
+			break
+		}
+		n = n.Children[i]
+		records = append(records, n.Record)
+	}
+	return records
+}
+
+// Line returns the moves leading to the current node.
+func (t *MoveTree) Line() []MoveRecord { return t.Records(t.Current) }
+
+// Play moves to the child of the current node drawing the edge of the record, adding it as a new variation
+// if no child draws that edge yet.
+func (t *MoveTree) Play(r MoveRecord) {
+	n := t.Node(t.Current)
+	if n == nil {
+		t.Current, n = nil, t.Root
+	}
+	for i, c := range n.Children {
+		if c.Record.MoveEdge == r.MoveEdge {
+			c.Record = r
+			n.Selected = i
+			t.Current = append(t.Current, i)
+			return
+		}
+	}
+	n.Children = append(n.Children, &MoveNode{Record: r})
+	n.Selected = len(n.Children) - 1
+	t.Current = append(t.Current, n.Selected)
+}
+
+// Goto moves to the node reached by playing the records from the root, adding the moves missing from the tree.
+func (t *MoveTree) Goto(records []MoveRecord) {
+	t.Current = nil
+	for _, r := range records {
+		t.Play(r)
+	}
+}
+
+// Undo moves to the parent of the current node, which keeps the undone move selected for Redo.
+// It returns false at the root.
+func (t *MoveTree) Undo() bool {
+	if len(t.Current) == 0 {
+		return false
+	}
+	t.Current = t.Current[:len(t.Current)-1]
+	return true
+}
+
+// Next returns the move that redo plays from the current node, the selected child, if any.
+func (t *MoveTree) Next() (MoveRecord, bool) {
+	n := t.Node(t.Current)
+	if n == nil || n.Selected < 0 || n.Selected >= len(n.Children) {
+		return MoveRecord{}, false
+	}
+	return n.Children[n.Selected].Record, true
+}
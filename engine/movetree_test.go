@@ -0,0 +1,107 @@
+package engine
+
+import (
+	"encoding/json"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+// treeRecords returns the records of the edges given in notation, played one after another on the board of size 4.
+func treeRecords(t *testing.T, edges ...string) []MoveRecord {
+	t.Helper()
+	tp := NewTopology(4)
+	var records []MoveRecord
+	for i, s := range edges {
+		e, err := tp.ParseEdge(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, MoveRecord{Step: i + 1, Player: Player1Turn, MoveEdge: e, Move: s})
+	}
+	return records
+}
+
+// moves returns the notation of the records.
+func moves(records []MoveRecord) []string {
+	var s []string
+	for _, r := range records {
+		s = append(s, r.Move)
+	}
+	return s
+}
+
+func TestMoveTreeVariations(t *testing.T) {
+	tree := NewMoveTree(4)
+	tree.Goto(treeRecords(t, "a1-b1", "a1-a2", "b1-c1"))
+
+	// A different move after undo branches at the same node
+	if !tree.Undo() || !tree.Undo() {
+		t.Fatal("cannot undo")
+	}
+	branch := treeRecords(t, "a1-b1", "b1-b2")
+	tree.Play(branch[1])
+	if root := tree.Root; len(root.Children) != 1 || len(root.Children[0].Children) != 2 {
+		t.Fatalf("expected one move with two variations, got %+v", root.Children)
+	}
+	if !slices.Equal(tree.Current, []int{0, 1}) || !slices.Equal(moves(tree.Line()), []string{"a1-b1", "b1-b2"}) {
+		t.Fatalf("at %v, line %v", tree.Current, moves(tree.Line()))
+	}
+	if _, ok := tree.Next(); ok {
+		t.Fatal("a new variation has a move to redo")
+	}
+
+	// Playing the first move again re-enters its variation, where redo follows the old line
+	tree.Undo()
+	tree.Play(treeRecords(t, "a1-b1", "a1-a2")[1])
+	n := tree.Node([]int{0})
+	if len(n.Children) != 2 || n.Selected != 0 || !slices.Equal(tree.Current, []int{0, 0}) {
+		t.Fatalf("re-entering the variation: %+v at %v", n.Children, tree.Current)
+	}
+	if r, ok := tree.Next(); !ok || r.Move != "b1-c1" {
+		t.Fatalf("redo plays %v (%v), expected b1-c1", r.Move, ok)
+	}
+
+	// Goto follows the moves already in the tree without adding any
+	tree.Goto(treeRecords(t, "a1-b1", "b1-b2"))
+	if len(n.Children) != 2 || !slices.Equal(tree.Current, []int{0, 1}) || n.Selected != 1 {
+		t.Fatalf("goto the second variation: %+v at %v", n.Children, tree.Current)
+	}
+	for tree.Undo() {
+	}
+	if len(tree.Current) != 0 || tree.Undo() {
+		t.Fatalf("undo past the root: %v", tree.Current)
+	}
+	if r, ok := tree.Next(); !ok || r.Move != "a1-b1" {
+		t.Fatalf("redo plays %v (%v) from the root, expected a1-b1", r.Move, ok)
+	}
+}
+
+func TestMoveTreeJSON(t *testing.T) {
+	tree := NewMoveTree(4)
+	tree.Goto(treeRecords(t, "a1-b1", "a1-a2", "b1-c1"))
+	tree.Goto(treeRecords(t, "a1-b1", "b1-b2", "c1-d1"))
+	tree.Goto(treeRecords(t, "a2-b2"))
+	tree.Goto(treeRecords(t, "a1-b1", "a1-a2"))
+
+	data, err := json.Marshal(tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var read MoveTree
+	if err := json.Unmarshal(data, &read); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&read, tree) {
+		t.Fatalf("read back %s as %+v", data, read)
+	}
+	if !slices.Equal(moves(read.Line()), []string{"a1-b1", "a1-a2"}) {
+		t.Fatalf("line %v after reading", moves(read.Line()))
+	}
+	if r, ok := read.Next(); !ok || r.Move != "b1-c1" {
+		t.Fatalf("redo plays %v (%v) after reading, expected b1-c1", r.Move, ok)
+	}
+	if records := read.Records([]int{0, 1, 0}); !slices.Equal(moves(records), []string{"a1-b1", "b1-b2", "c1-d1"}) {
+		t.Fatalf("second variation %v after reading", moves(records))
+	}
+}
@@ -0,0 +1,82 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// The network game protocol lets two instances play a game over a connection. The host owns the game: the joining
+// side sends a hello, the host answers with a welcome giving the seat of the joining side, then sends the state of
+// the game every time it changes. The joining side sends the edges it draws as moves, which the host validates before
+// playing them, so the joining side never changes the game by itself. Messages are JSON objects, one per line.
+// A Lobby speaks the same protocol to host many games at once, see lobby.go.
+
+// NetProtocolVersion is the version of the network game protocol. Both sides must speak the same version.
+const NetProtocolVersion = 1
+
+// Types of the messages of the network game protocol
+const (
+	NetHello   = "hello"   // Sent by the joining side to open the session
+	NetWelcome = "welcome" // Sent by the host with the seat of the joining side
+	NetState   = "state"   // Sent by the host with the board size and the moves of the game whenever it changes
+	NetMove    = "move"    // Sent by the joining side with the edge it draws, in notation
+	NetError   = "error"   // Sent by the host when it rejects a message
+	NetList    = "list"    // Sent to a lobby to list its games, of the board size if any
+	NetLobby   = "lobby"   // Sent by a lobby with its games
+	NetCreate  = "create"  // Sent to a lobby to open a game of the board size, against the engine if any
+	NetJoin    = "join"    // Sent to a lobby to take the free seat of the game
+	NetWatch   = "watch"   // Sent to a lobby to spectate the game
+	NetLeave   = "leave"   // Sent to a lobby to leave the game played or spectated
+)
+
+// NetMessage is a message of the network game protocol.
+type NetMessage struct {
+	Version   int           `json:"version"`             // Version of the protocol of the sender
+	Type      string        `json:"type"`                // Type of the message
+	Seat      Turn          `json:"seat,omitempty"`      // Player of the joining side, in a welcome, none for a spectator
+	BoardSize int           `json:"boardSize,omitempty"` // Number of dots on each side of the board, in a state
+	Records   []MoveRecord  `json:"records,omitempty"`   // Moves of the game, in a state
+	Move      string        `json:"move,omitempty"`      // Edge drawn, in a move
+	Error     string        `json:"error,omitempty"`     // Reason of the rejection, in an error
+	Game      int           `json:"game,omitempty"`      // ID of the game in a lobby
+	Engine    string        `json:"engine,omitempty"`    // Strategy of the engine playing the other seat, in a create
+	Games     []NetGameInfo `json:"games,omitempty"`     // Games of a lobby, in a lobby
+}
+
+// NetConn sends and receives the messages of the network game protocol over a connection.
+type NetConn struct {
+	rwc  io.ReadWriteCloser // Underlying connection
+	dec  *json.Decoder      // Decoder of the received messages
+	enc  *json.Encoder      // Encoder of the sent messages
+	lock sync.Mutex         // Mutex for send synchronization
+}
+
+// NewNetConn creates a network game connection over rwc.
+func NewNetConn(rwc io.ReadWriteCloser) *NetConn {
+	return &NetConn{rwc: rwc, dec: json.NewDecoder(rwc), enc: json.NewEncoder(rwc)}
+}
+
+// Send sends the message, stamped with the version of the protocol. It is safe for concurrent use.
+func (c *NetConn) Send(m NetMessage) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	m.Version = NetProtocolVersion
+	return c.enc.Encode(m)
+}
+
+// Receive waits for the next message, and rejects it if it was sent with another version of the protocol.
+func (c *NetConn) Receive() (NetMessage, error) {
+	var m NetMessage
+	if err := c.dec.Decode(&m); err != nil {
+		return m, err
+	}
+	if m.Version != NetProtocolVersion {
+		return m, fmt.Errorf("network protocol version %v, expected %v", m.Version, NetProtocolVersion)
+	}
+	return m, nil
+}
+
+// Close closes the connection, which makes a pending Receive fail.
+func (c *NetConn) Close() error { return c.rwc.Close() }
@@ -0,0 +1,447 @@
+package engine
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// DefaultNimberNodes is the default number of positions a NimberCalculator may search for one component.
+const DefaultNimberNodes = 1 << 12
+
+// maxNimberCache is the number of cached shapes, searched or too large, above which the cache is cleared.
+const maxNimberCache = 1 << 20
+
+// Component is a set of open boxes linked by free edges. In Nimstring, where completing a box means moving again
+// and the player who cannot move loses, the components of a board are independent games whose values add up as nimbers.
+type Component struct {
+	Boxes  []Box  // Open boxes of the component
+	Edges  []Edge // Free edges touching the boxes of the component
+	Nimber int    // Nim value of the component, or -1 if it could not be computed
+}
+
+// String returns a short description of the component.
+func (c Component) String() string {
+	if c.Nimber < 0 {
+		return fmt.Sprintf("%v boxes: *?", len(c.Boxes))
+	}
+	return fmt.Sprintf("%v boxes: *%v", len(c.Boxes), c.Nimber)
+}
+
+// scString is a free edge seen as a string of the strings-and-coins game, linking box a to box b or to the ground.
+type scString struct {
+	a, b Box
+}
+
+// NimberCalculator computes the Nimstring values of the components of a board.
+// Values are cached by the shape of the component, so a component keeps its value while the rest of the board changes
+// and equal shapes anywhere on any board are only searched once.
+// A NimberCalculator can be shared between goroutines.
+type NimberCalculator struct {
+	MaxNodes int // Maximum number of positions searched for one component
+
+	lock     sync.Mutex      // Mutex for cache synchronization
+	cache    map[string]int  // Nim values indexed by shape
+	tooLarge map[string]bool // Shapes of components that could not be searched within MaxNodes
+}
+
+// NewNimberCalculator creates a new nimber calculator with the default search limit.
+func NewNimberCalculator() *NimberCalculator {
+	return &NimberCalculator{MaxNodes: DefaultNimberNodes, cache: make(map[string]int), tooLarge: make(map[string]bool)}
+}
+
+// Components splits the open boxes of board b into components and computes their nim values.
+func (c *NimberCalculator) Components(t *Topology, b Board) []Component {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if len(c.cache)+len(c.tooLarge) > maxNimberCache {
+		c.cache, c.tooLarge = make(map[string]int), make(map[string]bool)
+	}
+	var comps []Component
+	used := make(map[Edge]bool)
+	for _, strings := range splitStrings(t, boardStrings(t, b)) {
+		comp := Component{Nimber: -1}
+		seen := make(map[Box]bool)
+		for _, s := range strings {
+			for _, box := range []Box{s.a, s.b} {
+				if box != ground && !seen[box] {
+					seen[box] = true
+					comp.Boxes = append(comp.Boxes, box)
+				}
+			}
+			e := edgeBetween(t, b, s, used)
+			used[e] = true
+			comp.Edges = append(comp.Edges, e)
+		}
+		sort.Slice(comp.Boxes, func(i, j int) bool { return comp.Boxes[i] < comp.Boxes[j] })
+		if key := shapeKey(t, strings); !loonyAfterCaptures(t, strings) && !c.tooLarge[key] {
+			budget := c.MaxNodes
+			if v, ok := c.value(t, strings, &budget); ok {
+				comp.Nimber = v
+			} else {
+				c.tooLarge[key] = true
+			}
+		}
+		comps = append(comps, comp)
+	}
+	return comps
+}
+
+// Nimber returns the Nimstring value of board b, the nim sum of its components.
+// It reports false if the opponent has just offered boxes with a loony move, where the value is not defined,
+// or if a component is too large to be searched.
+func (c *NimberCalculator) Nimber(t *Topology, b Board) (int, bool) {
+	nimber := 0
+	for _, comp := range c.Components(t, b) {
+		if comp.Nimber < 0 {
+			return 0, false
+		}
+		nimber ^= comp.Nimber
+	}
+	return nimber, true
+}
+
+// boardStrings returns the free edges of board b as strings.
+func boardStrings(t *Topology, b Board) []scString {
+	var strings []scString
+	for _, e := range t.Edges {
+		if b.Contains(e) {
+			continue
+		}
+		s := scString{a: ground, b: ground}
+		for _, box := range t.AdjacentBoxes(e) {
+			if s.a == ground {
+				s.a = box
+			} else {
+				s.b = box
+			}
+		}
+		strings = append(strings, s)
+	}
+	return strings
+}
+
+// edgeBetween returns the edge of the string: a free edge of board b linking its boxes that is not used yet,
+// since a box on the border may have several strings to the ground.
+func edgeBetween(t *Topology, b Board, s scString, used map[Edge]bool) Edge {
+	for _, e := range t.BoxEdges(s.a) {
+		if b.Contains(e) || used[e] {
+			continue
+		}
+		boxes := t.AdjacentBoxes(e)
+		if s.b == ground && len(boxes) == 1 {
+			return e
+		}
+		for _, box := range boxes {
+			if box == s.b {
+				return e
+			}
+		}
+	}
+	return InvalidEdge
+}
+
+// scDegrees holds the number of strings attached to each box, indexed by box value.
+type scDegrees []int
+
+// degrees returns the number of strings attached to each box of topology t.
+func degrees(t *Topology, strings []scString) scDegrees {
+	deg := make(scDegrees, len(t.boxEdges))
+	for _, s := range strings {
+		deg[s.a]++
+		if s.b != ground {
+			deg[s.b]++
+		}
+	}
+	return deg
+}
+
+// of returns the degree of the box, zero for the ground.
+func (deg scDegrees) of(box Box) int {
+	if box == ground {
+		return 0
+	}
+	return deg[box]
+}
+
+// splitStrings groups the strings by the component of their boxes.
+func splitStrings(t *Topology, strings []scString) [][]scString {
+	parent := make([]Box, len(t.boxEdges))
+	for i := range parent {
+		parent[i] = Box(i)
+	}
+	var find func(Box) Box
+	find = func(x Box) Box {
+		if parent[x] != x {
+			parent[x] = find(parent[x])
+		}
+		return parent[x]
+	}
+	for _, s := range strings {
+		if s.b != ground {
+			parent[find(s.a)] = find(s.b)
+		}
+	}
+	groups := make([]int, len(t.boxEdges))
+	var comps [][]scString
+	for _, s := range strings {
+		root := find(s.a)
+		if groups[root] == 0 {
+			comps = append(comps, nil)
+			groups[root] = len(comps)
+		}
+		comps[groups[root]-1] = append(comps[groups[root]-1], s)
+	}
+	return comps
+}
+
+// loony checks if the strings offer boxes with a loony move: a capturable box leading into a chain,
+// where the player to move can choose between taking everything and declining the last boxes to keep control.
+func loony(t *Topology, strings []scString) bool {
+	deg := degrees(t, strings)
+	for _, s := range strings {
+		for _, end := range [][2]Box{{s.a, s.b}, {s.b, s.a}} {
+			c, d := end[0], end[1]
+			if c == ground || d == ground || deg.of(c) != 1 || deg.of(d) != 2 {
+				continue
+			}
+			// A chain of three boxes with both ends capturable cannot be declined
+			for _, o := range strings {
+				if o != s && (o.a == d || o.b == d) {
+					e := o.a
+					if e == d {
+						e = o.b
+					}
+					if e == ground || deg.of(e) != 1 {
+						return true
+					}
+				}
+			}
+		}
+	}
+	return false
+}
+
+// loonyAfterCaptures checks if the strings offer boxes with a loony move, now or once boxes are captured one by one,
+// since a capture can leave the box behind it with two strings, leading into a chain.
+func loonyAfterCaptures(t *Topology, strings []scString) bool {
+	for {
+		if loony(t, strings) {
+			return true
+		}
+		i := capturable(t, strings)
+		if i < 0 {
+			return false
+		}
+		strings = append(strings[:i:i], strings[i+1:]...)
+	}
+}
+
+// loonyCut checks if cutting the string at index i is a loony move. The strings must contain no capturable box,
+// so only the boxes of the cut string can become capturable; deg holds the degrees before the cut.
+func loonyCut(strings []scString, deg scDegrees, i int) bool {
+	cut := strings[i]
+	degAfter := func(box Box) int {
+		d := deg.of(box)
+		if box == cut.a || box == cut.b {
+			d--
+		}
+		return d
+	}
+	// other returns the end of the first string of box, other than skip and the cut string, and its index.
+	other := func(box Box, skip int) (Box, int) {
+		for j, s := range strings {
+			if j == i || j == skip {
+				continue
+			}
+			if s.a == box {
+				return s.b, j
+			} else if s.b == box {
+				return s.a, j
+			}
+		}
+		return ground, -1
+	}
+	for _, c := range []Box{cut.a, cut.b} {
+		if c == ground || degAfter(c) != 1 {
+			continue
+		}
+		d, j := other(c, -1)
+		if d == ground || degAfter(d) != 2 {
+			continue
+		}
+		// A chain of three boxes with both ends capturable cannot be declined
+		if e, _ := other(d, j); e == ground || degAfter(e) != 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// capture removes the capturable boxes of the strings, which in Nimstring is always right outside of loony positions.
+func capture(t *Topology, strings []scString) []scString {
+	for {
+		i := capturable(t, strings)
+		if i < 0 {
+			return strings
+		}
+		strings = append(strings[:i:i], strings[i+1:]...)
+	}
+}
+
+// capturable returns the index of a string that captures a box when cut, or -1 if no box can be captured.
+func capturable(t *Topology, strings []scString) int {
+	deg := degrees(t, strings)
+	for i, s := range strings {
+		if deg.of(s.a) == 1 || deg.of(s.b) == 1 {
+			return i
+		}
+	}
+	return -1
+}
+
+// shapeKey returns a key identifying the strings up to a translation of the board.
+func shapeKey(t *Topology, strings []scString) string {
+	minX, minY := t.BoardSize, t.BoardSize
+	for _, s := range strings {
+		minX, minY = min(minX, t.X(Dot(s.a))), min(minY, t.Y(Dot(s.a)))
+		if s.b != ground {
+			minX, minY = min(minX, t.X(Dot(s.b))), min(minY, t.Y(Dot(s.b)))
+		}
+	}
+	codes := make([]uint32, len(strings))
+	for i, s := range strings {
+		code := func(box Box) uint32 {
+			if box == ground {
+				return 0xffff
+			}
+			return uint32(t.X(Dot(box))-minX)<<8 | uint32(t.Y(Dot(box))-minY)
+		}
+		a, b := code(s.a), code(s.b)
+		if a > b {
+			a, b = b, a
+		}
+		codes[i] = a<<16 | b
+	}
+	sort.Slice(codes, func(i, j int) bool { return codes[i] < codes[j] })
+	key := make([]byte, 4*len(codes))
+	for i, code := range codes {
+		binary.LittleEndian.PutUint32(key[4*i:], code)
+	}
+	return string(key)
+}
+
+// value returns the nim value of strings that contain no loony offer, searching at most budget new positions.
+func (c *NimberCalculator) value(t *Topology, strings []scString, budget *int) (int, bool) {
+	strings = capture(t, strings)
+	if len(strings) == 0 {
+		return 0, true
+	}
+	if comps := splitStrings(t, strings); len(comps) > 1 {
+		nimber := 0
+		for _, comp := range comps {
+			v, ok := c.value(t, comp, budget)
+			if !ok {
+				return 0, false
+			}
+			nimber ^= v
+		}
+		return nimber, true
+	}
+	key := shapeKey(t, strings)
+	if v, ok := c.cache[key]; ok {
+		return v, true
+	}
+	if *budget--; *budget < 0 {
+		return 0, false
+	}
+	options := make(map[int]bool)
+	tried := make(map[scString]bool)
+	deg := degrees(t, strings)
+	for i, s := range strings {
+		if tried[s] {
+			continue // Cutting either of two strings between the same boxes gives the same position
+		}
+		tried[s] = true
+		if loonyCut(strings, deg, i) {
+			continue // A loony move loses Nimstring
+		}
+		next := append(append(make([]scString, 0, len(strings)-1), strings[:i]...), strings[i+1:]...)
+		v, ok := c.value(t, next, budget)
+		if !ok {
+			return 0, false
+		}
+		options[v] = true
+	}
+	nimber := 0
+	for options[nimber] {
+		nimber++
+	}
+	c.cache[key] = nimber
+	return nimber, true
+}
+
+// nimstringPlayer plays like chainPlayer, but while safe edges remain it tries to win the fight for control
+// by moving to a position whose Nimstring value is zero.
+type nimstringPlayer struct {
+	chainPlayer
+	nimbers *NimberCalculator // Calculator of the component values
+}
+
+// BestEdge returns a move winning Nimstring when one can be found, and falls back to the chain strategy otherwise.
+func (n *nimstringPlayer) BestEdge(ctx context.Context, p Position, s Settings) Edge {
+	if p.Board.Size() >= p.EdgesCount() {
+		return InvalidEdge
+	}
+	if e, ok := solveEndgame(ctx, n.solver, p, s); ok {
+		return e
+	}
+	if e, ok := n.plannedEdge(p); ok {
+		return e
+	}
+	return GetBestEdge(ctx, p.Topology, p.Board, s.Goroutines, s.SearchTime)
+}
+
+// plannedEdge returns a move winning Nimstring while safe edges remain, or the edge chosen by the chain strategy.
+func (n *nimstringPlayer) plannedEdge(p Position) (Edge, bool) {
+	if a := Analyze(p); a.SafeEdges > 0 && !a.opened() {
+		if e, ok := n.winningEdge(p); ok {
+			return e, true
+		}
+	}
+	return n.chainPlayer.plannedEdge(p)
+}
+
+// RankEdges ranks the edges with flat Monte Carlo, putting the edge chosen from the Nimstring values first.
+func (n *nimstringPlayer) RankEdges(ctx context.Context, p Position, s Settings) []EdgeValue {
+	return rankPlanned(ctx, n.solver, p, s, n.plannedEdge)
+}
+
+// winningEdge returns a non-loony edge after which the Nimstring value is zero, preferring edges that give no box away.
+func (n *nimstringPlayer) winningEdge(p Position) (Edge, bool) {
+	if _, ok := n.nimbers.Nimber(p.Topology, p.Board); !ok {
+		return InvalidEdge, false
+	}
+	b := p.Board.Clone()
+	var sacrifice Edge
+	for _, class := range []int{safeEdge, unsafeEdge} {
+		for _, e := range p.Edges {
+			if b.Contains(e) || edgeClass(p.Topology, b, e) != class {
+				continue
+			}
+			nb := b.Clone()
+			nb.Add(e)
+			if v, ok := n.nimbers.Nimber(p.Topology, nb); ok && v == 0 {
+				if class == safeEdge {
+					return e, true
+				}
+				if sacrifice == InvalidEdge {
+					sacrifice = e
+				}
+			}
+		}
+	}
+	return sacrifice, sacrifice != InvalidEdge
+}
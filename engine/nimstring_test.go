@@ -0,0 +1,136 @@
+package engine
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"sort"
+	"testing"
+)
+
+// nimstringWins reports whether the player to move wins Nimstring on the strings played together with a nim heap,
+// searching every move: cutting the last string of a coin means moving again, and the player who cannot move loses.
+func nimstringWins(strings []scString, heap int, memo map[string]bool) bool {
+	codes := make([]string, len(strings))
+	for i, s := range strings {
+		codes[i] = fmt.Sprint(min(s.a, s.b), max(s.a, s.b))
+	}
+	sort.Strings(codes)
+	key := fmt.Sprint(heap, codes)
+	if win, ok := memo[key]; ok {
+		return win
+	}
+	win := false
+	for i, s := range strings {
+		next := append(append([]scString{}, strings[:i]...), strings[i+1:]...)
+		completed := false
+		for _, box := range []Box{s.a, s.b} {
+			completed = completed || box != ground && !slices.ContainsFunc(next, func(o scString) bool { return o.a == box || o.b == box })
+		}
+		if completed {
+			win = nimstringWins(next, heap, memo) // The player who completed a coin moves again
+		} else {
+			win = !nimstringWins(next, heap, memo)
+		}
+		if win {
+			break
+		}
+	}
+	for h := 0; h < heap && !win; h++ {
+		win = !nimstringWins(strings, h, memo)
+	}
+	memo[key] = win
+	return win
+}
+
+// bruteNimber returns the nim value of the strings: the size of the nim heap whose sum with them loses for the player
+// to move, or -1 if there is none, like after a loony move. The value cannot exceed the number of moves.
+func bruteNimber(strings []scString) int {
+	memo := make(map[string]bool)
+	for heap := 0; heap <= len(strings); heap++ {
+		if !nimstringWins(strings, heap, memo) {
+			return heap
+		}
+	}
+	return -1
+}
+
+// componentStrings returns the strings of the component, read back from its edges.
+func componentStrings(t *testing.T, tp *Topology, b Board, comp Component) []scString {
+	t.Helper()
+	var strings []scString
+	for i, e := range comp.Edges {
+		if e == InvalidEdge || b.Contains(e) || slices.Contains(comp.Edges[:i], e) {
+			t.Fatalf("%v: edge %v is drawn or listed twice", comp, tp.EdgeString(e))
+		}
+		s := scString{a: ground, b: ground}
+		for _, box := range tp.AdjacentBoxes(e) {
+			if s.a == ground {
+				s.a = box
+			} else {
+				s.b = box
+			}
+		}
+		strings = append(strings, s)
+	}
+	return strings
+}
+
+func TestKnownNimbers(t *testing.T) {
+	for _, c := range []struct {
+		name   string
+		size   int
+		free   []string
+		nimber int // -1 if the value is not defined
+	}{
+		{"isolated box", 2, []string{"a1-a2", "a1-b1", "b1-b2", "a2-b2"}, 1},
+		{"isolated box with three strings", 2, []string{"a1-a2", "a1-b1", "b1-b2"}, 0},
+		{"corner chain of 1", 4, []string{"a1-a2", "a1-b1"}, 1},
+		{"chain of 2", 4, []string{"a1-a2", "b1-b2", "b1-c1"}, 1},
+		{"chains of 1 and 2", 4, []string{"a1-a2", "a1-b1", "a3-a4", "b3-b4", "b4-c4"}, 0},
+		{"long chain", 4, []string{"a1-a2", "b1-b2", "c1-c2", "d1-d2"}, 0},
+		{"long chain of 5", 4, []string{"c1-d1", "c2-d2", "c3-d3", "c3-c4", "b3-b4", "a3-a4"}, 0},
+		{"long chain and chain of 1", 4, []string{"a1-a2", "b1-b2", "c1-c2", "d1-d2", "a4-b4", "a3-a4"}, 1},
+		{"loop of 4", 4, []string{"b1-b2", "a2-b2", "b2-c2", "b2-b3"}, 0},
+		{"opened long chain", 4, []string{"b1-b2", "c1-c2", "d1-d2"}, -1},
+		{"opened loop", 4, []string{"a2-b2", "b2-c2", "b2-b3"}, -1},
+	} {
+		p := freeEdgesPosition(t, c.size, c.free...)
+		nimber, ok := NewNimberCalculator().Nimber(p.Topology, p.Board)
+		if c.nimber < 0 && ok || c.nimber >= 0 && (!ok || nimber != c.nimber) {
+			t.Errorf("%v: *%v (%v), expected *%v", c.name, nimber, ok, c.nimber)
+		}
+		if brute := bruteNimber(boardStrings(p.Topology, p.Board)); brute != c.nimber {
+			t.Errorf("%v: brute force gives *%v, expected *%v", c.name, brute, c.nimber)
+		}
+	}
+}
+
+// TestNimbersMatchBruteForce compares the value of every component of random boards, and their sum,
+// with the brute-force mex over the strings listed by the component.
+func TestNimbersMatchBruteForce(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	c := NewNimberCalculator()
+	for _, size := range []int{3, 4} {
+		tp := NewTopology(size)
+		for i := 0; i < 500; i++ {
+			b := NewBoard()
+			for _, idx := range r.Perm(tp.EdgesCount())[:tp.EdgesCount()-4-r.Intn(8)] {
+				b.Add(tp.Edges[idx])
+			}
+			sum, defined := 0, true
+			for _, comp := range c.Components(tp, b) {
+				if brute := bruteNimber(componentStrings(t, tp, b, comp)); brute != comp.Nimber {
+					t.Fatalf("%v: %v, brute force gives *%v", Position{Topology: tp, Board: b, Turn: Player1Turn}, comp, brute)
+				}
+				if comp.Nimber < 0 {
+					defined = false // Only loony offers are left undefined on boards this small
+				}
+				sum ^= comp.Nimber
+			}
+			if nimber, ok := c.Nimber(tp, b); ok != defined || ok && nimber != sum {
+				t.Fatalf("%v: *%v (%v), the components sum to *%v (%v)", Position{Topology: tp, Board: b, Turn: Player1Turn}, nimber, ok, sum, defined)
+			}
+		}
+	}
+}
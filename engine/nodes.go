@@ -0,0 +1,50 @@
+package engine
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// nodeCounter counts the rollouts and tree iterations of the searches run with a context, and cancels the context
+// once a limit is reached.
+type nodeCounter struct {
+	nodes  atomic.Int64       // Rollouts and tree iterations so far
+	limit  int64              // Number of nodes cancelling the context, 0 for no limit
+	cancel context.CancelFunc // Cancels the context
+}
+
+// nodeCounterKey is the context key of the node counter.
+type nodeCounterKey struct{}
+
+// WithNodeLimit returns a copy of ctx counting the rollouts and tree iterations of the searches run with it, which is
+// cancelled once they reach nodes in total, so that searches can be limited by work instead of time.
+// A limit of 0 only counts the nodes, see Nodes.
+func WithNodeLimit(ctx context.Context, nodes int) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	c := &nodeCounter{limit: int64(max(nodes, 0)), cancel: cancel}
+	return context.WithValue(ctx, nodeCounterKey{}, c), cancel
+}
+
+// Nodes returns the number of rollouts and tree iterations counted by a context of WithNodeLimit, or 0 for other contexts.
+func Nodes(ctx context.Context) int {
+	if c := nodeCounterFrom(ctx); c != nil {
+		return int(c.nodes.Load())
+	}
+	return 0
+}
+
+// nodeCounterFrom returns the node counter of ctx, or nil if it has none.
+func nodeCounterFrom(ctx context.Context) *nodeCounter {
+	c, _ := ctx.Value(nodeCounterKey{}).(*nodeCounter)
+	return c
+}
+
+// add counts a node, cancelling the context at the limit. It does nothing on a nil counter.
+func (c *nodeCounter) add() {
+	if c == nil {
+		return
+	}
+	if n := c.nodes.Add(1); c.limit > 0 && n >= c.limit {
+		c.cancel()
+	}
+}
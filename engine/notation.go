@@ -0,0 +1,152 @@
+package engine
+
+import (
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+)
+
+// The notation names a dot by the letters of its column, a to z then aa, ab..., followed by the number of its row
+// from 1, like a1 for the top left dot. An edge is named by its two dots, like a1-b1, so that the name of an edge
+// does not depend on the board size. A position is written as <boardSize>/<turn>/<player1>-<player2>/<edges>,
+// where the turn is 1 or 2 and the drawn edges are a bitset in the order of the edges of the topology,
+// in hexadecimal digits of four edges each, like 3/1/0-0/000 for the start of a game on a board of 3 dots per side.
+
+// DotString returns the notation of the dot.
+func (t *Topology) DotString(d Dot) string {
+	return columnString(t.X(d)) + strconv.Itoa(t.Y(d)+1)
+}
+
+// EdgeString returns the notation of the edge.
+func (t *Topology) EdgeString(e Edge) string {
+	return t.DotString(t.Dot1(e)) + "-" + t.DotString(t.Dot2(e))
+}
+
+// columnString returns the letters of a column.
+func columnString(x int) string {
+	s := ""
+	for x++; x > 0; x = (x - 1) / 26 {
+		s = string(rune('a'+(x-1)%26)) + s
+	}
+	return s
+}
+
+// ParseDot parses the notation of a dot of the board.
+func (t *Topology) ParseDot(s string) (Dot, error) {
+	letters := strings.IndexFunc(s, func(r rune) bool { return r < 'a' || r > 'z' })
+	if letters <= 0 {
+		return 0, fmt.Errorf("invalid dot %q", s)
+	}
+	x := 0
+	for _, r := range s[:letters] {
+		// Stop before the column overflows
+		if x = x*26 + int(r-'a') + 1; x > t.BoardSize {
+			return 0, fmt.Errorf("dot %q is not on the board", s)
+		}
+	}
+	y, err := strconv.Atoi(s[letters:])
+	if err != nil || strconv.Itoa(y) != s[letters:] {
+		return 0, fmt.Errorf("invalid dot %q", s)
+	}
+	x, y = x-1, y-1
+	if x >= t.BoardSize || y < 0 || y >= t.BoardSize {
+		return 0, fmt.Errorf("dot %q is not on the board", s)
+	}
+	return t.NewDot(x, y), nil
+}
+
+// ParseEdge parses the notation of an edge of the board, its dots being in either order.
+func (t *Topology) ParseEdge(s string) (Edge, error) {
+	s1, s2, ok := strings.Cut(strings.ToLower(strings.TrimSpace(s)), "-")
+	if !ok {
+		return InvalidEdge, fmt.Errorf("invalid edge %q", s)
+	}
+	d1, err := t.ParseDot(s1)
+	if err != nil {
+		return InvalidEdge, err
+	}
+	d2, err := t.ParseDot(s2)
+	if err != nil {
+		return InvalidEdge, err
+	}
+	if d1 > d2 {
+		d1, d2 = d2, d1
+	}
+	e := t.NewEdge(d1, d2)
+	if !t.HasEdge(e) {
+		return InvalidEdge, fmt.Errorf("edge %q: %w", s, ErrInvalidEdge)
+	}
+	return e, nil
+}
+
+// String returns the notation of the position.
+func (p Position) String() string {
+	if p.Topology == nil {
+		return ""
+	}
+	turn := 1
+	if p.Turn == Player2Turn {
+		turn = 2
+	}
+	digits := make([]byte, (len(p.Edges)+3)/4)
+	for i := range digits {
+		var digit byte
+		for j := 0; j < 4 && 4*i+j < len(p.Edges); j++ {
+			if p.Board.Contains(p.Edges[4*i+j]) {
+				digit |= 1 << j
+			}
+		}
+		digits[i] = "0123456789abcdef"[digit]
+	}
+	return fmt.Sprintf("%v/%v/%v-%v/%s", p.BoardSize, turn, p.Player1Score, p.Player2Score, digits)
+}
+
+// ParsePosition parses the notation of a position.
+func ParsePosition(s string) (Position, error) {
+	fields := strings.Split(strings.TrimSpace(s), "/")
+	if len(fields) != 4 {
+		return Position{}, fmt.Errorf("invalid position %q", s)
+	}
+	boardSize, err := strconv.Atoi(fields[0])
+	if err != nil || boardSize < 2 || boardSize > MaxBoardSize {
+		return Position{}, fmt.Errorf("invalid board size %q of position", fields[0])
+	}
+	t := NewTopology(boardSize)
+	p := Position{Topology: t, Board: NewBitBoard(t)}
+	switch fields[1] {
+	case "1":
+		p.Turn = Player1Turn
+	case "2":
+		p.Turn = Player2Turn
+	default:
+		return Position{}, fmt.Errorf("invalid turn %q of position", fields[1])
+	}
+	score1, score2, ok := strings.Cut(fields[2], "-")
+	var err1, err2 error
+	p.Player1Score, err1 = strconv.Atoi(score1)
+	p.Player2Score, err2 = strconv.Atoi(score2)
+	if !ok || err1 != nil || err2 != nil || p.Player1Score < 0 || p.Player2Score < 0 {
+		return Position{}, fmt.Errorf("invalid scores %q of position", fields[2])
+	}
+	if len(fields[3]) != (len(t.Edges)+3)/4 {
+		return Position{}, fmt.Errorf("expected %v edge digits in position, got %v", (len(t.Edges)+3)/4, len(fields[3]))
+	}
+	for i, r := range fields[3] {
+		digit, err := strconv.ParseUint(string(r), 16, 8)
+		if err != nil {
+			return Position{}, fmt.Errorf("invalid edge digit %q of position", r)
+		}
+		for ; digit != 0; digit &= digit - 1 {
+			j := 4*i + bits.TrailingZeros64(digit)
+			if j >= len(t.Edges) {
+				return Position{}, fmt.Errorf("invalid edge digit %q of position", r)
+			}
+			p.Board.Add(t.Edges[j])
+		}
+	}
+	if p.Player1Score+p.Player2Score > len(t.Boxes) {
+		return Position{}, fmt.Errorf("scores %v exceed the %v boxes of the board", fields[2], len(t.Boxes))
+	}
+	return p, nil
+}
@@ -0,0 +1,101 @@
+package engine
+
+import (
+	"errors"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestEdgeNotationRoundTrip(t *testing.T) {
+	for _, size := range []int{2, 3, 5, MaxBoardSize, 28} {
+		tp := NewTopology(size)
+		for _, e := range tp.Edges {
+			s := tp.EdgeString(e)
+			for _, notation := range []string{s, strings.ToUpper(s), " " + s + " "} {
+				if parsed, err := tp.ParseEdge(notation); err != nil || parsed != e {
+					t.Fatalf("%vx%v: %q parsed as %v (%v), expected %v", size, size, notation, parsed, err, e)
+				}
+			}
+			d1, d2, _ := strings.Cut(s, "-")
+			if parsed, err := tp.ParseEdge(d2 + "-" + d1); err != nil || parsed != e {
+				t.Fatalf("%vx%v: %v-%v parsed as %v (%v), expected %v", size, size, d2, d1, parsed, err, e)
+			}
+		}
+	}
+	if tp := NewTopology(28); tp.EdgeString(tp.Edges[len(tp.Edges)-1]) != "ab27-ab28" {
+		t.Fatalf("last edge of the 28x28 board written %v", tp.EdgeString(tp.Edges[len(tp.Edges)-1]))
+	}
+}
+
+func TestParseEdgeRejects(t *testing.T) {
+	tp := NewTopology(3)
+	for _, s := range []string{
+		"", "a1", "a1b1", "-a1", "a1-", "1a-b1", "a01-b1", "a1-b1-c1", "a-b1", "a1-b",
+		"d1-d2",                    // Column out of range
+		"a4-b4", "a0-b0", "a-1-b1", // Row out of range
+		"a99999999999999999999-b1",   // Overflowing row number
+		"zzzzzzzzzzzzzzzzzzzzzz1-a1", // Overflowing column
+		"aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa1-a1",
+		"a1-b2", "a1-c1", "a1-a1", // Not an edge
+	} {
+		if e, err := tp.ParseEdge(s); err == nil {
+			t.Errorf("%q parsed as %v", s, e)
+		}
+	}
+	if _, err := tp.ParseEdge("a1-b2"); !errors.Is(err, ErrInvalidEdge) {
+		t.Errorf("diagonal: %v, expected ErrInvalidEdge", err)
+	}
+	for n := 1; n <= 40; n++ {
+		s := strings.Repeat("z", n) + "1"
+		if d, err := tp.ParseDot(s); err == nil {
+			t.Errorf("%q parsed as %v", s, d)
+		}
+	}
+}
+
+func TestPositionNotationRoundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, c := range []struct{ size, games int }{{2, 10}, {3, 10}, {4, 10}, {7, 5}, {MaxBoardSize, 1}} {
+		for game := 0; game < c.games; game++ {
+			g := NewGame(c.size)
+			for _, idx := range r.Perm(g.EdgesCount()) {
+				p := g.Position()
+				s := p.String()
+				q, err := ParsePosition(s)
+				if err != nil {
+					t.Fatalf("%v: %v", s, err)
+				}
+				if q.BoardSize != c.size || q.Turn != p.Turn || q.Player1Score != p.Player1Score || q.Player2Score != p.Player2Score {
+					t.Fatalf("%v parsed as %v", s, q)
+				}
+				for _, e := range p.Edges {
+					if q.Board.Contains(e) != p.Board.Contains(e) {
+						t.Fatalf("%v: edge %v differs", s, p.EdgeString(e))
+					}
+				}
+				if q.String() != s {
+					t.Fatalf("%v written back as %v", s, q)
+				}
+				if _, err := g.Play(g.Edges[idx]); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+	}
+}
+
+func TestParsePositionRejects(t *testing.T) {
+	for _, s := range []string{
+		"", "3/1/0-0", "3/1/0-0/000/0", "x/1/0-0/000",
+		"1/1/0-0/0", "21/1/0-0/" + strings.Repeat("0", 190), // Board size out of range
+		"3/0/0-0/000", "3/3/0-0/000", // Invalid turn
+		"3/1/0/000", "3/1/-1-0/000", "3/1/0-x/000", "3/1/3-2/000", // Invalid scores
+		"3/1/0-0/00", "3/1/0-0/0", "3/1/0-0/", // Truncated edges
+		"3/1/0-0/0000", "3/1/0-0/00g", "3/1/0-0/0-1", // Invalid edges
+	} {
+		if p, err := ParsePosition(s); err == nil {
+			t.Errorf("%q parsed as %v", s, p)
+		}
+	}
+}
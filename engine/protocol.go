@@ -0,0 +1,252 @@
+package engine
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The engine protocol is a line-based text protocol, similar in spirit to UCI, between a client running the game and
+// an engine choosing the moves. The client sends one command per line:
+//
+//	dab                                     identify the engine, which replies id name <name> then dabok
+//	isready                                 reply readyok once the running search, if any, is over
+//	setoption name <name> value <value>     set Strategy, Goroutines, Exploration or SolverThreshold
+//	newgame <boardSize>                     start a game on a board with the given number of dots on each side
+//	position startpos [moves <edge>...]     set the position reached by the moves from the start of the game
+//	position notation <position>            set the position written in notation, whatever the board size
+//	go [movetime <ms>] [nodes <n>]          search the position, then reply info nodes <n> time <ms>
+//	                                        and bestmove <edge>, or bestmove none if the game is over
+//	stop                                    stop the running search, which replies at once
+//	quit                                    stop the engine
+//
+// Edges and positions are written in notation, like a1-b1, see Topology.EdgeString and Position.String. Errors are replied as info string <error>, and unknown commands are ignored.
+
+// Defaults of the engine protocol
+const (
+	ProtocolEngineName = "Dots-and-Boxes" // Name replied to dab, followed by the strategy
+	ProtocolMaxTime    = time.Hour        // Search time of go commands limited by nodes only
+	ProtocolBoardSize  = 6                // Number of dots on each side of the board until the first newgame
+)
+
+// protocolServer is the engine side of the protocol, searching with a registered strategy.
+type protocolServer struct {
+	out          io.Writer          // Replies to the client
+	outLock      sync.Mutex         // Mutex for reply synchronization
+	strategyName string             // Name of the strategy
+	strategy     Strategy           // Strategy searching the positions
+	settings     Settings           // Search settings, the search time being the default of go commands
+	position     Position           // Position to search
+	cancel       context.CancelFunc // Stops the running search, nil if none is running
+	searching    sync.WaitGroup     // Running search
+}
+
+// ServeProtocol runs an engine over the protocol, reading commands from r and replying to w until quit or the end of r.
+// It searches with a new instance of the named strategy and the given settings, which the client may change.
+func ServeProtocol(r io.Reader, w io.Writer, strategy string, s Settings) error {
+	st, err := NewStrategy(strategy)
+	if err != nil {
+		return err
+	}
+	s.Normalize()
+	srv := &protocolServer{out: w, strategyName: strategy, strategy: st, settings: s}
+	srv.newGame(ProtocolBoardSize)
+	defer srv.stop()
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		cmd, args := fields[0], fields[1:]
+		if cmd == "stop" {
+			srv.stop()
+			continue
+		}
+		srv.searching.Wait()
+		if cmd == "quit" {
+			return nil
+		}
+		if err := srv.handle(cmd, args); err != nil {
+			srv.reply("info string %v", err)
+		}
+	}
+	return scanner.Err()
+}
+
+// reply writes a line to the client.
+func (srv *protocolServer) reply(format string, a ...any) {
+	srv.outLock.Lock()
+	defer srv.outLock.Unlock()
+	fmt.Fprintf(srv.out, format+"\n", a...)
+}
+
+// stop stops the running search and waits for its reply.
+func (srv *protocolServer) stop() {
+	srv.outLock.Lock()
+	if srv.cancel != nil {
+		srv.cancel()
+	}
+	srv.outLock.Unlock()
+	srv.searching.Wait()
+}
+
+// newGame sets the start position of a board with the given number of dots on each side.
+func (srv *protocolServer) newGame(boardSize int) {
+	srv.position = NewGame(boardSize).Position()
+}
+
+// handle runs a command other than stop and quit, with no search running.
+func (srv *protocolServer) handle(cmd string, args []string) error {
+	switch cmd {
+	case "dab":
+		srv.reply("id name %v %v", ProtocolEngineName, srv.strategyName)
+		srv.reply("dabok")
+	case "isready":
+		srv.reply("readyok")
+	case "setoption":
+		return srv.setOption(args)
+	case "newgame":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: newgame <boardSize>")
+		}
+		boardSize, err := strconv.Atoi(args[0])
+		if err != nil || boardSize < 2 || boardSize > MaxBoardSize {
+			return fmt.Errorf("invalid board size %q", args[0])
+		}
+		srv.newGame(boardSize)
+	case "position":
+		p, err := parsePosition(srv.position.Topology, args)
+		if err != nil {
+			return err
+		}
+		srv.position = p
+	case "go":
+		return srv.search(args)
+	}
+	return nil
+}
+
+// setOption sets an option of the engine from the arguments of setoption.
+func (srv *protocolServer) setOption(args []string) error {
+	if len(args) != 4 || args[0] != "name" || args[2] != "value" {
+		return fmt.Errorf("usage: setoption name <name> value <value>")
+	}
+	name, value := args[1], args[3]
+	var err error
+	switch name {
+	case "Strategy":
+		var st Strategy
+		if st, err = NewStrategy(value); err == nil {
+			srv.strategyName, srv.strategy = value, st
+		}
+	case "Goroutines":
+		var n int
+		if n, err = strconv.Atoi(value); err == nil && n > 0 {
+			srv.settings.Goroutines = n
+		}
+	case "Exploration":
+		var x float64
+		if x, err = strconv.ParseFloat(value, 64); err == nil && x > 0 {
+			srv.settings.Exploration = x
+		}
+	case "SolverThreshold":
+		srv.settings.SolverThreshold, err = strconv.Atoi(value)
+	default:
+		return fmt.Errorf("unknown option %q", name)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid value %q of option %v: %v", value, name, err)
+	}
+	return nil
+}
+
+// parsePosition parses the arguments of a position command on topology t.
+func parsePosition(t *Topology, args []string) (Position, error) {
+	if len(args) == 0 {
+		return Position{}, fmt.Errorf("usage: position startpos [moves <edge>...] | position notation <position>")
+	}
+	switch args[0] {
+	case "startpos":
+		g := NewGame(t.BoardSize)
+		if len(args) > 1 {
+			if args[1] != "moves" {
+				return Position{}, fmt.Errorf("expected moves, got %q", args[1])
+			}
+			for _, arg := range args[2:] {
+				e, err := g.ParseEdge(arg)
+				if err != nil {
+					return Position{}, err
+				}
+				if _, err := g.Play(e); err != nil {
+					return Position{}, fmt.Errorf("move %v: %v", arg, err)
+				}
+			}
+		}
+		return g.Position(), nil
+	case "notation":
+		if len(args) != 2 {
+			return Position{}, fmt.Errorf("usage: position notation <position>")
+		}
+		return ParsePosition(args[1])
+	default:
+		return Position{}, fmt.Errorf("unknown position %q", args[0])
+	}
+}
+
+// search starts searching the position in the background with the limits of the arguments of go.
+func (srv *protocolServer) search(args []string) error {
+	s := srv.settings
+	nodes := 0
+	limited := false
+	for i := 0; i < len(args); i += 2 {
+		if i+1 == len(args) {
+			return fmt.Errorf("missing value of %v", args[i])
+		}
+		n, err := strconv.Atoi(args[i+1])
+		if err != nil || n <= 0 {
+			return fmt.Errorf("invalid value %q of %v", args[i+1], args[i])
+		}
+		switch args[i] {
+		case "movetime":
+			s.SearchTime, limited = time.Duration(n)*time.Millisecond, true
+		case "nodes":
+			nodes = n
+		default:
+			return fmt.Errorf("unknown limit %q", args[i])
+		}
+	}
+	if nodes > 0 && !limited {
+		s.SearchTime = ProtocolMaxTime
+	}
+
+	ctx, cancel := WithNodeLimit(context.Background(), nodes)
+	srv.outLock.Lock()
+	srv.cancel = cancel
+	srv.outLock.Unlock()
+	srv.searching.Add(1)
+	p, st := srv.position, srv.strategy
+	p.Board = p.Board.Clone()
+	go func() {
+		defer srv.searching.Done()
+		start := time.Now()
+		e := st.BestEdge(ctx, p, s)
+		srv.outLock.Lock()
+		srv.cancel = nil
+		srv.outLock.Unlock()
+		cancel()
+		srv.reply("info nodes %v time %v", Nodes(ctx), time.Since(start).Milliseconds())
+		if e == InvalidEdge {
+			srv.reply("bestmove none")
+			return
+		}
+		srv.reply("bestmove %v", p.EdgeString(e))
+	}()
+	return nil
+}
@@ -0,0 +1,201 @@
+package engine
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// serveScript runs the engine protocol on the commands of the script and returns its replies, leaving out the
+// info nodes lines whose numbers vary between runs.
+func serveScript(t *testing.T, script ...string) []string {
+	t.Helper()
+	var out strings.Builder
+	if err := ServeProtocol(strings.NewReader(strings.Join(script, "\n")), &out, GreedyStrategy, Settings{Goroutines: 1}); err != nil {
+		t.Fatal(err)
+	}
+	var replies []string
+	for _, line := range strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n") {
+		if line != "" && !strings.HasPrefix(line, "info nodes ") {
+			replies = append(replies, line)
+		}
+	}
+	return replies
+}
+
+func TestProtocolCommands(t *testing.T) {
+	for _, c := range []struct {
+		name    string
+		script  []string
+		replies []string
+	}{
+		{"handshake", []string{"dab", "isready"}, []string{"id name Dots-and-Boxes Greedy", "dabok", "readyok"}},
+		{"unknown command", []string{"hello", "", "isready"}, []string{"readyok"}},
+		{
+			"newgame",
+			[]string{"newgame", "newgame x", "newgame 1", "newgame 21", "newgame 20"},
+			[]string{
+				"info string usage: newgame <boardSize>",
+				`info string invalid board size "x"`,
+				`info string invalid board size "1"`,
+				`info string invalid board size "21"`,
+			},
+		},
+		{
+			"setoption",
+			[]string{
+				"setoption name Goroutines", "setoption name Depth value 3", "setoption name Goroutines value x",
+				"setoption name Strategy value Nope", "setoption name Strategy value " + ChainStrategy, "dab",
+			},
+			[]string{
+				"info string usage: setoption name <name> value <value>",
+				`info string unknown option "Depth"`,
+				`info string invalid value "x" of option Goroutines: strconv.Atoi: parsing "x": invalid syntax`,
+				`info string invalid value "Nope" of option Strategy: unknown strategy: Nope`,
+				"id name Dots-and-Boxes " + ChainStrategy, "dabok",
+			},
+		},
+		{
+			"position",
+			[]string{
+				"position", "position endpos", "position startpos edges a1-b1", "position startpos moves a1-c1",
+				"position notation", "position notation 21/1/0-0/0",
+			},
+			[]string{
+				"info string usage: position startpos [moves <edge>...] | position notation <position>",
+				`info string unknown position "endpos"`,
+				`info string expected moves, got "edges"`,
+				`info string edge "a1-c1": edge is not on the board`,
+				"info string usage: position notation <position>",
+				`info string invalid board size "21" of position`,
+			},
+		},
+		{
+			"go limits",
+			[]string{"go movetime", "go movetime 0", "go depth 3"},
+			[]string{"info string missing value of movetime", `info string invalid value "0" of movetime`, `info string unknown limit "depth"`},
+		},
+		{
+			"go",
+			[]string{"newgame 2", "position startpos moves a1-b1 a1-a2 b1-b2", "go nodes 10", "position startpos moves a1-b1 a1-a2 b1-b2 a2-b2", "go"},
+			[]string{"bestmove a2-b2", "bestmove none"},
+		},
+		{
+			"go from notation",
+			[]string{"position notation " + freeEdgesPosition(t, 3, "a1-a2", "a2-a3", "a3-b3").String(), "go movetime 10"},
+			[]string{"bestmove a1-a2"}, // The only box to complete
+		},
+	} {
+		if replies := serveScript(t, c.script...); !slices.Equal(replies, c.replies) {
+			t.Errorf("%v: replied %q, expected %q", c.name, replies, c.replies)
+		}
+	}
+}
+
+// TestProtocolStop checks that stop ends a search limited by nodes only, which would otherwise run for an hour.
+func TestProtocolStop(t *testing.T) {
+	r, w := io.Pipe()
+	out, replies := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		done <- ServeProtocol(r, replies, MCTSStrategy, Settings{Goroutines: 1})
+		replies.Close()
+	}()
+	lines := bufio.NewScanner(out)
+	if _, err := io.WriteString(w, "go nodes 1000000000\nstop\n"); err != nil {
+		t.Fatal(err)
+	}
+	for lines.Scan() && !strings.HasPrefix(lines.Text(), "bestmove ") {
+	}
+	if !strings.HasPrefix(lines.Text(), "bestmove ") || lines.Text() == "bestmove none" {
+		t.Fatalf("replied %q to stop", lines.Text())
+	}
+	w.Close()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+// newPipeEngine connects an ExternalEngine to an engine served in-process over pipes.
+func newPipeEngine(t *testing.T, serve func(r io.Reader, w io.Writer)) *ExternalEngine {
+	t.Helper()
+	commands, in := io.Pipe()
+	out, replies := io.Pipe()
+	go func() {
+		serve(commands, replies)
+		replies.Close()
+	}()
+	x, err := newExternalEngine("pipe", nil, in, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { x.Close() })
+	return x
+}
+
+func TestExternalEngine(t *testing.T) {
+	x := newPipeEngine(t, func(r io.Reader, w io.Writer) { ServeProtocol(r, w, GreedyStrategy, Settings{}) })
+	if x.Name != "Dots-and-Boxes Greedy" {
+		t.Fatalf("engine named %q", x.Name)
+	}
+	s := Settings{Goroutines: 1, SearchTime: 10 * time.Millisecond}
+	for _, c := range []struct {
+		free []string
+		edge string
+	}{
+		{[]string{"a1-a2", "a2-a3", "a3-b3"}, "a1-a2"},
+		{[]string{"c2-c3", "a2-a3", "a3-b3"}, "c2-c3"},
+	} {
+		p := freeEdgesPosition(t, 3, c.free...)
+		if e := x.BestEdge(context.Background(), p, s); p.EdgeString(e) != c.edge || x.Err() != nil {
+			t.Fatalf("%v: played %v (%v), expected %v", p, p.EdgeString(e), x.Err(), c.edge)
+		}
+	}
+	if e := x.BestEdge(context.Background(), freeEdgesPosition(t, 3), s); e != InvalidEdge {
+		t.Fatalf("played %v once the game is over", e)
+	}
+
+	// A search stopped by ctx still answers
+	x = newPipeEngine(t, func(r io.Reader, w io.Writer) { ServeProtocol(r, w, MCTSStrategy, Settings{}) })
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	p := NewGame(5).Position()
+	start := time.Now()
+	if e := x.BestEdge(ctx, p, Settings{Goroutines: 1, SearchTime: time.Hour}); e == InvalidEdge || x.Err() != nil {
+		t.Fatalf("played %v (%v) when stopped", e, x.Err())
+	}
+	if elapsed := time.Since(start); elapsed > ExternalEngineTimeout {
+		t.Fatalf("stopped after %v", elapsed)
+	}
+}
+
+// TestExternalEngineIllegalEdge checks that the rollout policy plays instead of an engine answering a drawn edge.
+func TestExternalEngineIllegalEdge(t *testing.T) {
+	x := newPipeEngine(t, func(r io.Reader, w io.Writer) {
+		commands := bufio.NewScanner(r)
+		for commands.Scan() {
+			switch strings.Fields(commands.Text())[0] {
+			case "dab":
+				io.WriteString(w, "id name Cheater\ndabok\n")
+			case "isready":
+				io.WriteString(w, "readyok\n")
+			case "go":
+				io.WriteString(w, "bestmove a1-b1\n")
+			}
+		}
+	})
+	if x.Name != "Cheater" {
+		t.Fatalf("engine named %q", x.Name)
+	}
+	p := freeEdgesPosition(t, 3, "a1-a2", "b2-c2")
+	if e := x.BestEdge(context.Background(), p, Settings{Goroutines: 1, SearchTime: time.Millisecond}); e == InvalidEdge || p.Board.Contains(e) {
+		t.Fatalf("played %v", p.EdgeString(e))
+	}
+	if err := x.Err(); err == nil || !strings.Contains(err.Error(), "illegal edge a1-b1") {
+		t.Fatalf("error %v, expected an illegal edge", err)
+	}
+}
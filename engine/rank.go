@@ -0,0 +1,143 @@
+package engine
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// EdgeValue is the value of drawing an edge.
+type EdgeValue struct {
+	Edge   Edge    // Edge to draw
+	Margin float64 // Expected final margin of the game from the point of view of the player to move, NaN if unknown
+	Exact  bool    // Whether the margin is proven by the endgame solver
+}
+
+// Ranker is implemented by strategies that can tell how good the edges they consider are.
+type Ranker interface {
+	// RankEdges runs the search of BestEdge and returns the edges it considered,
+	// the edge BestEdge would draw first and the others from the most to the least promising.
+	RankEdges(ctx context.Context, p Position, s Settings) []EdgeValue
+}
+
+// RankEdges returns the edges of position p considered by strategy st, the edge it would draw first.
+// Strategies that are not rankers only give the edge they would draw, with an unknown margin.
+func RankEdges(ctx context.Context, st Strategy, p Position, s Settings) []EdgeValue {
+	if r, ok := st.(Ranker); ok {
+		return r.RankEdges(ctx, p, s)
+	}
+	if e := st.BestEdge(ctx, p, s); e != InvalidEdge {
+		return []EdgeValue{{Edge: e, Margin: math.NaN()}}
+	}
+	return nil
+}
+
+// EvaluatePosition evaluates every free edge of position p with rollouts for the search time of the settings,
+// and returns their expected final margins in the order of the edges of the topology.
+func EvaluatePosition(ctx context.Context, p Position, s Settings) []EdgeValue {
+	stats := EvaluateEdges(ctx, p.Topology, p.Board, s.Goroutines, s.SearchTime)
+	var values []EdgeValue
+	for _, e := range p.Edges {
+		if p.Board.Contains(e) {
+			continue
+		}
+		v := EdgeValue{Edge: e, Margin: math.NaN()}
+		if st := stats[e]; st.Rollouts > 0 {
+			v.Margin = float64(p.margin()) + st.Average()
+		}
+		values = append(values, v)
+	}
+	return values
+}
+
+// margin returns the current margin of the position from the point of view of the player to move.
+func (p Position) margin() int {
+	if p.Turn == Player2Turn {
+		return p.Player2Score - p.Player1Score
+	}
+	return p.Player1Score - p.Player2Score
+}
+
+// next returns the position reached by drawing edge e.
+func (p Position) next(e Edge) Position {
+	n := p
+	n.Board = p.Board.Clone()
+	captured := p.ObtainsScore(p.Board, e)
+	n.Board.Add(e)
+	switch {
+	case captured == 0:
+		n.Turn = -p.Turn
+	case p.Turn == Player1Turn:
+		n.Player1Score += captured
+	default:
+		n.Player2Score += captured
+	}
+	return n
+}
+
+// rankEndgame solves every edge of the position if it has fewer free edges than the threshold of the settings,
+// and returns them by decreasing exact margin. If ctx is done, the edges solved so far are returned.
+func rankEndgame(ctx context.Context, solver *Solver, p Position, s Settings) ([]EdgeValue, bool) {
+	if s.SolverThreshold <= 0 || p.EdgesCount()-p.Board.Size() >= s.SolverThreshold || p.Board.Size() >= p.EdgesCount() {
+		return nil, false
+	}
+	var values []EdgeValue
+	for _, e := range p.Edges {
+		if p.Board.Contains(e) {
+			continue
+		}
+		n := p.next(e)
+		_, margin, err := solver.Solve(ctx, n)
+		if err != nil {
+			break
+		}
+		if n.Turn != p.Turn {
+			margin = -margin
+		}
+		values = append(values, EdgeValue{Edge: e, Margin: float64(margin), Exact: true})
+	}
+	sort.SliceStable(values, func(i, j int) bool { return values[i].Margin > values[j].Margin })
+	return values, true
+}
+
+// rankPlanned ranks the edges by their average rollout margin, or by their exact margin in the endgame.
+// If planned is not nil and chooses an edge, that edge is put first.
+func rankPlanned(ctx context.Context, solver *Solver, p Position, s Settings, planned func(Position) (Edge, bool)) []EdgeValue {
+	if values, ok := rankEndgame(ctx, solver, p, s); ok {
+		return values
+	}
+	if p.Board.Size() >= p.EdgesCount() {
+		return nil
+	}
+	searchTimes, sumScores := flatSearch(ctx, p.Topology, p.Board, s.Goroutines, s.SearchTime, nil)
+	var values []EdgeValue
+	for _, e := range p.Edges {
+		if n := searchTimes[e]; n > 0 {
+			values = append(values, EdgeValue{Edge: e, Margin: float64(p.margin()) + float64(sumScores[e])/float64(n)})
+		}
+	}
+	sort.SliceStable(values, func(i, j int) bool { return values[i].Margin > values[j].Margin })
+	if planned != nil {
+		if e, ok := planned(p); ok {
+			return promote(values, e)
+		}
+	}
+	if len(values) == 0 {
+		values = promote(values, getNextEdges(p.Topology, p.Board, rand.New(rand.NewSource(time.Now().UnixNano()))))
+	}
+	return values
+}
+
+// promote moves edge e to the front of values, adding it with an unknown margin if it is missing.
+func promote(values []EdgeValue, e Edge) []EdgeValue {
+	for i, v := range values {
+		if v.Edge == e {
+			copy(values[1:i+1], values[:i])
+			values[0] = v
+			return values
+		}
+	}
+	return append([]EdgeValue{{Edge: e, Margin: math.NaN()}}, values...)
+}
@@ -0,0 +1,120 @@
+package engine
+
+import (
+	"context"
+	"math"
+)
+
+// Labels of the moves of a game review
+const (
+	OkMove         = "ok"         // The move lost less than InaccuracyMargin
+	InaccuracyMove = "inaccuracy" // The move lost at least InaccuracyMargin
+	BlunderMove    = "blunder"    // The move lost at least BlunderMargin
+)
+
+// Margins lost from which a move is labelled as an inaccuracy or a blunder
+const (
+	InaccuracyMargin = 1.0 // Margin lost by an inaccuracy
+	BlunderMargin    = 3.0 // Margin lost by a blunder
+)
+
+// MoveReview is the analysis of a move of a game.
+type MoveReview struct {
+	Step       int     `json:"step"`       // Step number of the move
+	Player     Turn    `json:"player"`     // Player who made the move
+	MoveEdge   Edge    `json:"moveEdge"`   // Edge drawn by the player
+	BestEdge   Edge    `json:"bestEdge"`   // Edge recommended by the engine
+	BestMargin float64 `json:"bestMargin"` // Expected final margin after the best edge, from the point of view of the player
+	MoveMargin float64 `json:"moveMargin"` // Expected final margin after the move, from the point of view of the player
+	MarginLost float64 `json:"marginLost"` // Margin lost by the move compared to the best edge
+	Exact      bool    `json:"exact"`      // Whether the margins are proven by the endgame solver
+	Label      string  `json:"label"`      // Label of the move, see OkMove
+}
+
+// GameReview is the analysis of every move of a game.
+type GameReview struct {
+	BoardSize    int          `json:"boardSize"`    // Number of dots on each side of the board
+	Strategy     string       `json:"strategy"`     // Name of the strategy that reviewed the game, set by the caller
+	Moves        []MoveReview `json:"moves"`        // Analysis of each move, in order
+	Inaccuracies [2]int       `json:"inaccuracies"` // Number of inaccuracies of Player 1 and Player 2
+	Blunders     [2]int       `json:"blunders"`     // Number of blunders of Player 1 and Player 2
+}
+
+// ReviewGame replays the move records on a board of the given size like Game.Replay, and ranks the edges of every
+// position with strategy st. The margin of a move is the value of the position it reaches, so a move agreeing with
+// the engine never loses any margin. progress, if not nil, is called after each position is searched.
+// If ctx is done, ReviewGame gives up and returns the error of ctx.
+func ReviewGame(ctx context.Context, boardSize int, records []MoveRecord, st Strategy, s Settings, progress func(done, total int)) (GameReview, error) {
+	review := GameReview{BoardSize: boardSize}
+	g := NewGame(boardSize)
+	positions := make([]Position, 0, len(records)+1)
+	edges := make([]Edge, 0, len(records))
+	for _, r := range records {
+		positions = append(positions, g.Position())
+		e, err := g.recordEdge(r)
+		if err != nil {
+			return review, err
+		}
+		if _, err := g.Play(e); err != nil {
+			return review, err
+		}
+		edges = append(edges, e)
+	}
+
+	// Value of each position from the point of view of its player to move, and the best edge
+	values := make([]EdgeValue, len(positions))
+	for i, p := range positions {
+		if err := ctx.Err(); err != nil {
+			return review, err
+		}
+		ranked := RankEdges(ctx, st, p, s)
+		if len(ranked) > 0 {
+			values[i] = ranked[0]
+		}
+		if progress != nil {
+			progress(i+1, len(positions))
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return review, err
+	}
+
+	for i, r := range records {
+		m := MoveReview{
+			Step:       r.Step,
+			Player:     r.Player,
+			MoveEdge:   edges[i],
+			BestEdge:   values[i].Edge,
+			BestMargin: values[i].Margin,
+			Exact:      values[i].Exact,
+		}
+		if i+1 < len(records) {
+			m.MoveMargin = values[i+1].Margin
+			m.Exact = m.Exact && values[i+1].Exact
+			if positions[i+1].Turn != r.Player && m.MoveMargin != 0 {
+				m.MoveMargin = -m.MoveMargin
+			}
+		} else {
+			m.MoveMargin = float64(g.Score(r.Player) - g.Score(-r.Player))
+		}
+		if m.MoveEdge != m.BestEdge && !math.IsNaN(m.BestMargin) && !math.IsNaN(m.MoveMargin) {
+			m.MarginLost = max(0, m.BestMargin-m.MoveMargin)
+		}
+		player := 0
+		if r.Player == Player2Turn {
+			player = 1
+		}
+		switch {
+		case m.MarginLost >= BlunderMargin:
+			m.Label = BlunderMove
+			review.Blunders[player]++
+		case m.MarginLost >= InaccuracyMargin:
+			m.Label = InaccuracyMove
+			review.Inaccuracies[player]++
+		default:
+			m.Label = OkMove
+		}
+		review.Moves = append(review.Moves, m)
+	}
+	return review, nil
+}
@@ -0,0 +1,43 @@
+package engine
+
+import (
+	"context"
+	"testing"
+)
+
+// TestReviewGameReadsNotation reviews records whose edges are only given in notation, like those of a game log.
+func TestReviewGameReadsNotation(t *testing.T) {
+	g := NewGame(3)
+	for !g.Over() {
+		for _, e := range g.Edges {
+			if !g.Board().Contains(e) {
+				if _, err := g.Play(e); err != nil {
+					t.Fatal(err)
+				}
+				break
+			}
+		}
+	}
+	records := g.Records()
+	for i := range records {
+		records[i].MoveEdge = InvalidEdge
+	}
+	strategy, err := NewStrategy(GreedyStrategy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	review, err := ReviewGame(context.Background(), 3, records, strategy, Settings{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, m := range review.Moves {
+		if m.MoveEdge != g.Records()[i].MoveEdge {
+			t.Fatalf("move %v: reviewed %v, played %v", i+1, g.EdgeString(m.MoveEdge), records[i].Move)
+		}
+	}
+
+	records[0].Move = "a1-c1"
+	if _, err := ReviewGame(context.Background(), 3, records, strategy, Settings{}, nil); err == nil {
+		t.Fatal("reviewed a move that is not an edge")
+	}
+}
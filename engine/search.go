@@ -0,0 +1,252 @@
+package engine
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// getNextEdges evaluates and selects the next best edge to draw on the board.
+// It returns the edge that either immediately obtains a score or minimizes the opponent's potential score.
+// The scan starts at a random edge so that equally good edges are picked with similar probability.
+func getNextEdges(t *Topology, b Board, r *rand.Rand) (bestEdge Edge) {
+	if bb, ok := b.(*BitBoard); ok && bb.t == t {
+		return bb.nextEdge(r)
+	}
+	enemyMinScore := 3
+	offset := r.Intn(len(t.Edges))
+	for i := range t.Edges {
+		e := t.Edges[(i+offset)%len(t.Edges)]
+		// Check if the edge is not a part of the board
+		if !b.Contains(e) {
+			// Check if drawing this edge obtains a score
+			if score := t.ObtainsScore(b, e); score > 0 {
+				return e // Immediately return if it obtains a score
+			} else if score == 0 {
+				// Evaluate the potential score the opponent might gain
+				boxes := t.AdjacentBoxes(e)
+				enemyScore := 0
+				for _, box := range boxes {
+					if t.EdgesCountInBox(b, box) == 2 {
+						enemyScore++ // Increment if the opponent could score here
+					}
+				}
+				// Select the edge that minimizes the appointment's Engine potential score
+				if enemyMinScore > enemyScore {
+					enemyMinScore = enemyScore
+					bestEdge = e
+				}
+			}
+		}
+	}
+	return
+}
+
+// Rollout plays the game on board b until all edges are drawn, using the greedy rollout policy for both sides.
+// It returns the first edge drawn and the final margin from the point of view of the player to move.
+func Rollout(t *Topology, b Board, r *rand.Rand) (firstEdge Edge, score int) {
+	turn := Player1Turn
+	for b.Size() < t.EdgesCount() {
+		edge := getNextEdges(t, b, r)
+		if firstEdge == InvalidEdge {
+			firstEdge = edge
+		}
+		s := t.ObtainsScore(b, edge)
+		score += int(turn) * s
+		if s == 0 {
+			ChangeTurn(&turn)
+		}
+		b.Add(edge)
+	}
+	return
+}
+
+// resetBoard makes dst a copy of src, reusing the memory of dst when both are bitset boards.
+func resetBoard(dst, src Board) Board {
+	if d, ok := dst.(*BitBoard); ok {
+		if s, ok := src.(*BitBoard); ok && d.t == s.t {
+			d.CopyFrom(s)
+			return d
+		}
+	}
+	return src.Clone()
+}
+
+// GetBestEdge performs a multithreaded search to determine the best edge to draw on board b.
+// It uses multiple goroutines to simulate the game and gather statistics on edge performance.
+// The search stops early when ctx is done, returning the best edge found so far.
+func GetBestEdge(ctx context.Context, t *Topology, b Board, goroutines int, searchTime time.Duration) (bestEdge Edge) {
+	if b.Size() >= t.EdgesCount() {
+		return InvalidEdge
+	}
+	globalSearchTime, globalSumScore := flatSearch(ctx, t, b, goroutines, searchTime, nil)
+
+	// Determine the best edge based on the highest average score
+	bestScore := -1e9
+	for e, score := range globalSumScore {
+		averageScore := float64(score) / float64(globalSearchTime[e])
+		if averageScore > bestScore {
+			bestEdge = e
+			bestScore = averageScore
+		}
+	}
+	// Fall back to the rollout policy if no simulation finished in time
+	if bestEdge == InvalidEdge {
+		bestEdge = getNextEdges(t, b, rand.New(rand.NewSource(time.Now().UnixNano())))
+	}
+	return
+}
+
+// EdgeStats are the rollout statistics of an edge drawn first.
+type EdgeStats struct {
+	Rollouts int // Number of rollouts starting with the edge
+	SumScore int // Sum of the margins of those rollouts from the point of view of the player to move
+}
+
+// Average returns the average margin of the rollouts starting with the edge.
+func (s EdgeStats) Average() float64 { return float64(s.SumScore) / float64(s.Rollouts) }
+
+// SearchStats runs the search of GetBestEdge on board b and returns the statistics it builds for the edges drawn first.
+// The rollout policy only draws the edges it considers best, so the other free edges get no statistics.
+func SearchStats(ctx context.Context, t *Topology, b Board, goroutines int, searchTime time.Duration) map[Edge]EdgeStats {
+	return edgeStats(flatSearch(ctx, t, b, goroutines, searchTime, nil))
+}
+
+// EvaluateEdges is like SearchStats, but the rollouts start with every free edge of board b in turn,
+// so that each of them gets statistics.
+func EvaluateEdges(ctx context.Context, t *Topology, b Board, goroutines int, searchTime time.Duration) map[Edge]EdgeStats {
+	var free []Edge
+	for _, e := range t.Edges {
+		if !b.Contains(e) {
+			free = append(free, e)
+		}
+	}
+	if len(free) == 0 {
+		return map[Edge]EdgeStats{}
+	}
+	return edgeStats(flatSearch(ctx, t, b, goroutines, searchTime, free))
+}
+
+// edgeStats merges the rollout counts and margin sums of flatSearch.
+func edgeStats(searchTimes, sumScores map[Edge]int) map[Edge]EdgeStats {
+	stats := make(map[Edge]EdgeStats, len(searchTimes))
+	for e, n := range searchTimes {
+		stats[e] = EdgeStats{Rollouts: n, SumScore: sumScores[e]}
+	}
+	return stats
+}
+
+// firstEdgeRollout draws edge e on board b, plays the rest of the game with the rollout policy,
+// and returns the final margin from the point of view of the player who drew e.
+func firstEdgeRollout(t *Topology, b Board, e Edge, r *rand.Rand) int {
+	captured := t.ObtainsScore(b, e)
+	b.Add(e)
+	_, score := Rollout(t, b, r)
+	if captured > 0 {
+		return captured + score // The player who drew the edge moves again
+	}
+	return -score
+}
+
+// flatSearch plays rollouts from board b with the given number of goroutines for the given time, or until ctx is done.
+// Every rollout counts as a node of WithNodeLimit.
+// The rollouts start with the edges of first in turn, or with the edge chosen by the rollout policy if first is empty.
+// It returns the number of rollouts starting with each edge and the sum of their margins.
+func flatSearch(ctx context.Context, t *Topology, b Board, goroutines int, searchTime time.Duration, first []Edge) (globalSearchTime, globalSumScore map[Edge]int) {
+	counter := nodeCounterFrom(ctx)
+	ctx, cancel := context.WithTimeout(ctx, searchTime)
+	defer cancel()
+	// Maps to store global search times and scores for each edge
+	globalSearchTime = make(map[Edge]int)
+	globalSumScore = make(map[Edge]int)
+	// Slice of maps to store local search times and scores for each goroutine
+	localSearchTimes := make([]map[Edge]int, goroutines)
+	localSumScores := make([]map[Edge]int, goroutines)
+	var wg sync.WaitGroup
+	wg.Add(goroutines)
+	// Launch multiple goroutines for parallel edge evaluation
+	for i := 0; i < goroutines; i++ {
+		localSearchTime := make(map[Edge]int)
+		localSumScore := make(map[Edge]int)
+		localSearchTimes[i] = localSearchTime
+		localSumScores[i] = localSumScore
+		r := rand.New(rand.NewSource(time.Now().UnixNano() + int64(i)))
+		go func() {
+			defer wg.Done()
+			var cb Board
+			for next := i; ; next += goroutines {
+				select {
+				case <-ctx.Done():
+					return // Exit when the search time is up or the search is cancelled
+				default:
+					// Simulate the game from a copy of the board state
+					cb = resetBoard(cb, b)
+					var firstEdge Edge
+					var score int
+					if len(first) > 0 {
+						firstEdge = first[next%len(first)]
+						score = firstEdgeRollout(t, cb, firstEdge, r)
+					} else {
+						firstEdge, score = Rollout(t, cb, r)
+					}
+					// Update local statistics for the first edge chosen
+					localSearchTime[firstEdge]++
+					localSumScore[firstEdge] += score
+					counter.add()
+				}
+			}
+		}()
+	}
+	wg.Wait() // Wait for all goroutines to finish
+
+	// Aggregate local statistics into global statistics
+	for i := range goroutines {
+		for e, s := range localSearchTimes[i] {
+			globalSearchTime[e] += s
+		}
+		for e, s := range localSumScores[i] {
+			globalSumScore[e] += s
+		}
+	}
+	return
+}
+
+// WinProbability estimates the probability that Player 1 wins from position p, a draw counting as half a win.
+// If solver is not nil and fewer free edges than threshold are left, the position is solved exactly;
+// otherwise the estimate is the share of the given number of rollouts won by Player 1.
+func WinProbability(ctx context.Context, solver *Solver, threshold int, p Position, rollouts int) float64 {
+	result := func(margin int) float64 {
+		switch {
+		case margin > 0:
+			return 1
+		case margin < 0:
+			return 0
+		default:
+			return 0.5
+		}
+	}
+	margin := p.Player1Score - p.Player2Score
+	free := p.EdgesCount() - p.Board.Size()
+	if free == 0 {
+		return result(margin)
+	}
+	if solver != nil && free < threshold {
+		if _, m, err := solver.Solve(ctx, p); err == nil {
+			return result(m * int(p.Turn))
+		}
+	}
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	var wins float64
+	var cb Board
+	n := 0
+	for ; n < rollouts && ctx.Err() == nil; n++ {
+		cb = resetBoard(cb, p.Board)
+		_, score := Rollout(p.Topology, cb, r)
+		wins += result(margin + score*int(p.Turn))
+	}
+	if n == 0 {
+		return 0.5
+	}
+	return wins / float64(n)
+}
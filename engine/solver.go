@@ -0,0 +1,251 @@
+package engine
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"sync"
+)
+
+// DefaultSolverThreshold is the default number of free edges below which the exact solver takes over from the search.
+const DefaultSolverThreshold = 24
+
+// SolverDisabled is the solver threshold that disables the exact solver. Unlike 0, it is kept by Settings.Normalize.
+const SolverDisabled = -1
+
+// solverTableBits is the base-2 logarithm of the number of slots of the transposition table.
+const solverTableBits = 20
+
+// Bounds of the value stored in a transposition table slot
+const (
+	exactBound = iota + 1 // The value is exact
+	lowerBound            // The value is a lower bound of the exact value
+	upperBound            // The value is an upper bound of the exact value
+)
+
+// solverSlot is a slot of the transposition table.
+type solverSlot struct {
+	hash  uint64 // Zobrist hash of the position, 0 for an empty slot
+	value int16  // Margin of the remaining boxes from the point of view of the player to move
+	best  int16  // Index of the best edge found, or -1
+	bound int8   // Kind of bound of the value
+}
+
+// Solver is an exact endgame solver. It runs a negamax search with alpha-beta pruning over the free edges
+// and caches the positions it has solved in a Zobrist-hashed transposition table,
+// which is kept between calls so that the solutions of the previous moves are reused.
+// A Solver can be shared between goroutines, but only one solve runs at a time.
+type Solver struct {
+	solveLock sync.Mutex      // Serializes solves
+	t         *Topology       // Topology of the cached positions
+	keys      []uint64        // Zobrist key of each edge index
+	table     []solverSlot    // Transposition table indexed by the low bits of the hash
+	open      int             // Number of boxes not completed yet in the position being searched
+	moves     [][]int         // Scratch move lists, one for each search depth
+	ctx       context.Context // Context of the running solve
+	nodes     int             // Number of positions searched by the running solve
+	cancelled bool            // Whether the running solve has been cancelled
+}
+
+// NewSolver creates a new endgame solver.
+func NewSolver() *Solver { return &Solver{} }
+
+// Solve searches the position to the end and returns the optimal edge for the player to move,
+// together with the final margin of the game from the point of view of that player when both sides play perfectly.
+// The time needed grows exponentially with the number of free edges, see DefaultSolverThreshold.
+// If ctx is done before the position is solved, Solve gives up and returns the error of ctx.
+func (s *Solver) Solve(ctx context.Context, p Position) (bestEdge Edge, margin int, err error) {
+	s.solveLock.Lock()
+	defer s.solveLock.Unlock()
+	margin = p.Player1Score - p.Player2Score
+	if p.Turn == Player2Turn {
+		margin = -margin
+	}
+	if p.Board.Size() >= p.EdgesCount() {
+		return InvalidEdge, margin, nil
+	}
+	s.reset(p.Topology)
+	s.ctx, s.nodes, s.cancelled = ctx, 0, false
+	defer func() { s.ctx = nil }()
+	b := toBitBoard(p.Topology, p.Board)
+	var hash uint64
+	for idx := range p.Edges {
+		if b.bits[idx>>6]&(1<<(idx&63)) != 0 {
+			hash ^= s.keys[idx]
+		}
+	}
+	s.open = 0
+	for _, box := range p.Boxes {
+		if b.counts[box] < 4 {
+			s.open++
+		}
+	}
+	value, best := s.negamax(b, hash, -s.open, s.open, 0)
+	if s.cancelled {
+		return InvalidEdge, margin, ctx.Err()
+	}
+	return p.Edges[best], margin + value, nil
+}
+
+// reset prepares the solver for positions of topology t, dropping the cached positions of another topology.
+func (s *Solver) reset(t *Topology) {
+	if s.t == t {
+		return
+	}
+	r := rand.New(rand.NewSource(int64(t.BoardSize)))
+	s.t = t
+	s.keys = make([]uint64, t.EdgesCount())
+	for i := range s.keys {
+		s.keys[i] = r.Uint64() | 1
+	}
+	s.table = make([]solverSlot, 1<<solverTableBits)
+	s.moves = make([][]int, t.EdgesCount()+1)
+}
+
+// toBitBoard returns a bitset copy of board b.
+func toBitBoard(t *Topology, b Board) *BitBoard {
+	if bb, ok := b.(*BitBoard); ok && bb.t == t {
+		return bb.Clone().(*BitBoard)
+	}
+	bb := NewBitBoard(t).(*BitBoard)
+	for _, e := range t.Edges {
+		if b.Contains(e) {
+			bb.Add(e)
+		}
+	}
+	return bb
+}
+
+// negamax returns the margin of the remaining boxes of board b from the point of view of the player to move,
+// and the index of the best edge. The value is exact if it lies strictly between alpha and beta,
+// otherwise it is a bound on the side of the window it fell on.
+func (s *Solver) negamax(b *BitBoard, hash uint64, alpha, beta, depth int) (int, int) {
+	// Give up once the solve is cancelled
+	if s.nodes++; s.nodes%1024 == 0 && s.ctx.Err() != nil {
+		s.cancelled = true
+	}
+	if s.cancelled {
+		return 0, -1
+	}
+
+	// Nobody can win more than the boxes still open, so a window beyond them is decided without searching
+	if alpha >= s.open {
+		return s.open, -1
+	}
+	if beta <= -s.open {
+		return -s.open, -1
+	}
+	alpha = max(alpha, -s.open)
+	beta = min(beta, s.open)
+
+	slot := &s.table[hash&(1<<solverTableBits-1)]
+	ttBest := -1
+	if slot.hash == hash {
+		ttBest = int(slot.best)
+		v := int(slot.value)
+		switch {
+		case slot.bound == exactBound,
+			slot.bound == lowerBound && v >= beta,
+			slot.bound == upperBound && v <= alpha:
+			return v, ttBest
+		}
+	}
+
+	moves := s.orderMoves(b, ttBest, depth)
+	alpha0 := alpha
+	bestValue, best := math.MinInt, -1
+	for _, idx := range moves {
+		captured := b.addIndex(idx)
+		s.open -= captured
+		var v int
+		if b.size == len(b.t.Edges) {
+			v = captured
+		} else if captured > 0 {
+			// The player who completed a box moves again
+			cv, _ := s.negamax(b, hash^s.keys[idx], alpha-captured, beta-captured, depth+1)
+			v = captured + cv
+		} else {
+			cv, _ := s.negamax(b, hash^s.keys[idx], -beta, -alpha, depth+1)
+			v = -cv
+		}
+		s.open += captured
+		b.removeIndex(idx)
+		if v > bestValue {
+			bestValue, best = v, idx
+		}
+		alpha = max(alpha, v)
+		if alpha >= beta {
+			break
+		}
+	}
+
+	if s.cancelled {
+		return bestValue, best // The value is not reliable, so it must not be stored
+	}
+	bound := int8(exactBound)
+	if bestValue <= alpha0 {
+		bound = upperBound
+	} else if bestValue >= beta {
+		bound = lowerBound
+	}
+	*slot = solverSlot{hash: hash, value: int16(bestValue), best: int16(best), bound: bound}
+	return bestValue, best
+}
+
+// orderMoves returns the free edges of board b worth searching, the most promising first.
+// A box that can be completed without changing any other box is a free point for the player to move,
+// so taking it is always optimal and no other move is searched.
+func (s *Solver) orderMoves(b *BitBoard, ttBest, depth int) []int {
+	moves := s.moves[depth][:0]
+	var safes, unsafes []int
+	for idx := range b.t.Edges {
+		if b.bits[idx>>6]&(1<<(idx&63)) != 0 {
+			continue
+		}
+		independent := true
+		switch b.edgeClass(idx) {
+		case captureEdge:
+			for _, box := range b.layout.edgeBoxes[idx] {
+				independent = independent && b.counts[box] == 3
+			}
+			if independent {
+				return append(moves, idx)
+			}
+			moves = append(moves, idx)
+		case safeEdge:
+			safes = append(safes, idx)
+		default:
+			unsafes = append(unsafes, idx)
+		}
+	}
+	moves = append(append(moves, safes...), unsafes...)
+	for i, idx := range moves {
+		if idx == ttBest {
+			copy(moves[1:i+1], moves[:i])
+			moves[0] = idx
+			break
+		}
+	}
+	s.moves[depth] = moves
+	return moves
+}
+
+// solveEndgame returns the optimal edge if the position has fewer free edges than the threshold of the settings.
+// A cancelled solve still counts as handled, so that callers return at once.
+func solveEndgame(ctx context.Context, solver *Solver, p Position, s Settings) (Edge, bool) {
+	if s.SolverThreshold <= 0 || p.EdgesCount()-p.Board.Size() >= s.SolverThreshold || p.Board.Size() >= p.EdgesCount() {
+		return InvalidEdge, false
+	}
+	e, _, _ := solver.Solve(ctx, p)
+	return e, true
+}
+
+// ponderEndgame solves the position of the opponent if every position reached by its move is left to the solver,
+// so that the transposition table already holds their solutions. It reports whether the position was left to the solver.
+func ponderEndgame(ctx context.Context, solver *Solver, p Position, s Settings) bool {
+	if s.SolverThreshold <= 0 || p.EdgesCount()-p.Board.Size() > s.SolverThreshold || p.Board.Size() >= p.EdgesCount() {
+		return false
+	}
+	solver.Solve(ctx, p)
+	return true
+}
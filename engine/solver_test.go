@@ -0,0 +1,209 @@
+package engine
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// minimax returns the margin of the remaining boxes of board b from the point of view of the player to move,
+// searching every move without pruning. Solved positions are kept in memo, keyed by their drawn edges.
+func minimax(t *Topology, b Board, memo map[uint64]int) int {
+	var key uint64
+	for _, e := range t.Edges {
+		if b.Contains(e) {
+			key |= 1 << t.EdgeIndex(e)
+		}
+	}
+	if v, ok := memo[key]; ok {
+		return v
+	}
+	best, free := 0, false
+	for _, e := range t.Edges {
+		if b.Contains(e) {
+			continue
+		}
+		v := moveMargin(t, b, e, memo)
+		if !free || v > best {
+			best, free = v, true
+		}
+	}
+	memo[key] = best
+	return best
+}
+
+// moveMargin returns the margin of the remaining boxes of board b for the player drawing e, both sides playing perfectly after it.
+func moveMargin(t *Topology, b Board, e Edge, memo map[uint64]int) int {
+	captured := len(t.ObtainsBoxes(b, e))
+	b.Add(e)
+	defer b.Remove(e)
+	if captured > 0 {
+		return captured + minimax(t, b, memo) // The player who completed a box moves again
+	}
+	return -minimax(t, b, memo)
+}
+
+// checkSolve solves p and checks the margin and the edge against the exhaustive minimax.
+func checkSolve(t *testing.T, s *Solver, p Position, memo map[uint64]int) {
+	t.Helper()
+	base := p.Player1Score - p.Player2Score
+	if p.Turn == Player2Turn {
+		base = -base
+	}
+	e, margin, err := s.Solve(context.Background(), p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := base + minimax(p.Topology, p.Board, memo); margin != expected {
+		t.Fatalf("%v: margin %v, expected %v", p, margin, expected)
+	}
+	if e == InvalidEdge || p.Board.Contains(e) {
+		t.Fatalf("%v: edge %v is not free", p, e)
+	}
+	if reached := base + moveMargin(p.Topology, p.Board, e, memo); reached != margin {
+		t.Fatalf("%v: edge %v reaches the margin %v, expected %v", p, p.EdgeString(e), reached, margin)
+	}
+}
+
+// latePosition plays random moves on a board of the given size until free edges are left.
+func latePosition(r *rand.Rand, size, free int) Position {
+	g := NewGame(size)
+	for _, idx := range r.Perm(g.EdgesCount())[:g.EdgesCount()-free] {
+		if _, err := g.Play(g.Edges[idx]); err != nil {
+			panic(err)
+		}
+	}
+	return g.Position()
+}
+
+// TestSolveSmallBoards solves every position reached on the 2x2 and 3x3 dot boards by random games.
+func TestSolveSmallBoards(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for size := 2; size <= 3; size++ {
+		memo := make(map[uint64]int)
+		s := NewSolver()
+		for game := 0; game < 50; game++ {
+			g := NewGame(size)
+			for !g.Over() {
+				checkSolve(t, s, g.Position(), memo)
+				var free []Edge
+				for _, e := range g.Edges {
+					if !g.Board().Contains(e) {
+						free = append(free, e)
+					}
+				}
+				if _, err := g.Play(free[r.Intn(len(free))]); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+	}
+}
+
+// TestSolveLatePositions solves random late positions of larger boards, then solves them again
+// from the transposition table and after the solver was used on another topology.
+func TestSolveLatePositions(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	for _, size := range []int{4, 5} {
+		var positions []Position
+		for i := 0; i < 10; i++ {
+			positions = append(positions, latePosition(r, size, 10+r.Intn(5)))
+		}
+		s := NewSolver()
+		for pass := 0; pass < 2; pass++ {
+			memo := make(map[uint64]int)
+			for _, p := range positions {
+				checkSolve(t, s, p, memo) // The second pass reads the table filled by the first one
+			}
+		}
+		s.Solve(context.Background(), latePosition(r, 3, 6))
+		memo := make(map[uint64]int)
+		for _, p := range positions {
+			checkSolve(t, s, p, memo)
+		}
+	}
+}
+
+// TestSolverTableBounds solves positions of the 3x3 dot board with one solver, and checks after each solve
+// that every position in the transposition table holds a bound of its exact value.
+func TestSolverTableBounds(t *testing.T) {
+	r := rand.New(rand.NewSource(4))
+	memo := make(map[uint64]int)
+	s := NewSolver()
+	for i := 0; i < 50; i++ {
+		p := latePosition(r, 3, 4+r.Intn(8))
+		if _, _, err := s.Solve(context.Background(), p); err != nil {
+			t.Fatal(err)
+		}
+		seen := make(map[uint64]bool)
+		var walk func(b *BitBoard, hash uint64)
+		walk = func(b *BitBoard, hash uint64) {
+			if seen[hash] {
+				return
+			}
+			seen[hash] = true
+			if slot := s.table[hash&(1<<solverTableBits-1)]; slot.hash == hash {
+				v, value := minimax(p.Topology, b, memo), int(slot.value)
+				if slot.bound == exactBound && v != value || slot.bound == lowerBound && v < value || slot.bound == upperBound && v > value {
+					t.Fatalf("%v: the table holds %+v, the exact value is %v", Position{Topology: p.Topology, Board: b, Turn: Player1Turn}, slot, v)
+				}
+			}
+			for idx, e := range p.Edges {
+				if !b.Contains(e) {
+					b.addIndex(idx)
+					walk(b, hash^s.keys[idx])
+					b.removeIndex(idx)
+				}
+			}
+		}
+		b := toBitBoard(p.Topology, p.Board)
+		var hash uint64
+		for idx, e := range p.Edges {
+			if b.Contains(e) {
+				hash ^= s.keys[idx]
+			}
+		}
+		walk(b, hash)
+	}
+}
+
+// TestSolveEndgameThreshold checks that the solver takes over exactly below the threshold of the settings.
+func TestSolveEndgameThreshold(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	p := latePosition(r, 4, 12)
+	memo := make(map[uint64]int)
+	optimal := minimax(p.Topology, p.Board, memo)
+	for _, c := range []struct {
+		threshold int
+		solved    bool
+	}{
+		{SolverDisabled, false},
+		{0, false},
+		{12, false},
+		{13, true},
+		{DefaultSolverThreshold, true},
+	} {
+		e, ok := solveEndgame(context.Background(), NewSolver(), p, Settings{SolverThreshold: c.threshold})
+		if ok != c.solved {
+			t.Fatalf("threshold %v with 12 free edges: solved %v, expected %v", c.threshold, ok, c.solved)
+		}
+		if ok && moveMargin(p.Topology, p.Board, e, memo) != optimal {
+			t.Fatalf("threshold %v: edge %v is not optimal", c.threshold, p.EdgeString(e))
+		}
+	}
+	if _, ok := solveEndgame(context.Background(), NewSolver(), latePosition(r, 4, 0), Settings{SolverThreshold: DefaultSolverThreshold}); ok {
+		t.Fatal("solved a finished game")
+	}
+
+	// Below the threshold, a search too short to find anything still plays the optimal edge
+	strategy, err := NewStrategy(MCTSStrategy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := Settings{Goroutines: 1, SearchTime: time.Millisecond, SolverThreshold: 13}
+	s.Normalize()
+	if e := strategy.BestEdge(context.Background(), p, s); moveMargin(p.Topology, p.Board, e, memo) != optimal {
+		t.Fatalf("%v played %v below the solver threshold, which is not optimal", MCTSStrategy, p.EdgeString(e))
+	}
+}
@@ -0,0 +1,213 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Names of the built-in strategies
+const (
+	GreedyStrategy     = "Greedy"     // Greedy rollout policy, without search
+	MonteCarloStrategy = "MonteCarlo" // Flat Monte Carlo over the first edge of each rollout
+	MCTSStrategy       = "MCTS"       // Monte Carlo Tree Search with UCT selection
+	ChainStrategy      = "Chain"      // Chain analysis with double-dealing in the endgame
+	NimstringStrategy  = "Nimstring"  // Chain analysis playing for Nimstring values in the middle game
+)
+
+// DefaultStrategy is the strategy used when none is configured.
+const DefaultStrategy = MonteCarloStrategy
+
+// Settings configures a search.
+type Settings struct {
+	Goroutines  int           `json:"goroutines"`  // Number of goroutines for the search
+	SearchTime  time.Duration `json:"searchTime"`  // Time duration for the search
+	Exploration float64       `json:"exploration"` // UCT exploration constant, used by MCTS
+	// Number of free edges below which the exact endgame solver takes over; negative disables the solver
+	SolverThreshold int `json:"solverThreshold"`
+}
+
+// DefaultSettings returns the default search settings.
+func DefaultSettings() Settings {
+	return Settings{
+		Goroutines:      runtime.NumCPU(),
+		SearchTime:      time.Second,
+		Exploration:     DefaultExploration,
+		SolverThreshold: DefaultSolverThreshold,
+	}
+}
+
+// Normalize replaces the unset fields of the settings with their default values.
+func (s *Settings) Normalize() {
+	d := DefaultSettings()
+	if s.Goroutines <= 0 {
+		s.Goroutines = d.Goroutines
+	}
+	if s.SearchTime <= 0 {
+		s.SearchTime = d.SearchTime
+	}
+	if s.Exploration <= 0 {
+		s.Exploration = d.Exploration
+	}
+	if s.SolverThreshold == 0 {
+		s.SolverThreshold = d.SolverThreshold
+	}
+}
+
+// Position is a snapshot of a game that strategies search from.
+type Position struct {
+	*Topology
+	Board        Board // Edges drawn so far
+	Turn         Turn  // Player to move
+	Player1Score int   // Score of Player 1
+	Player2Score int   // Score of Player 2
+}
+
+// Position returns a snapshot of the game that stays valid while the game goes on.
+func (g *Game) Position() Position {
+	return Position{
+		Topology:     g.Topology,
+		Board:        g.board.Clone(),
+		Turn:         g.turn,
+		Player1Score: g.player1Score,
+		Player2Score: g.player2Score,
+	}
+}
+
+// Strategy chooses the edge to draw in a position.
+type Strategy interface {
+	// BestEdge returns the edge to draw, or InvalidEdge if the game is over.
+	// It must return soon after ctx is done; the edge returned then may be a poor one.
+	BestEdge(ctx context.Context, p Position, s Settings) Edge
+}
+
+// Ponderer is implemented by strategies that can prepare their next search while the opponent is thinking.
+type Ponderer interface {
+	// Ponder searches position p, where the opponent is to move, until ctx is done or nothing is left to learn,
+	// and keeps what it learns for the next call of BestEdge from a position the opponent reaches.
+	Ponder(ctx context.Context, p Position, s Settings)
+}
+
+var (
+	strategiesLock sync.Mutex                     // Mutex for strategy registry synchronization
+	strategies     = map[string]func() Strategy{} // Registered strategy constructors
+	strategyNames  []string                       // Registered strategy names, in registration order
+)
+
+// RegisterStrategy makes a strategy available under the given name.
+// newStrategy is called once for every player using the strategy, so stateful strategies are not shared.
+func RegisterStrategy(name string, newStrategy func() Strategy) {
+	strategiesLock.Lock()
+	defer strategiesLock.Unlock()
+	if _, ok := strategies[name]; !ok {
+		strategyNames = append(strategyNames, name)
+	}
+	strategies[name] = newStrategy
+}
+
+// NewStrategy creates a new instance of the strategy registered under the given name.
+func NewStrategy(name string) (Strategy, error) {
+	strategiesLock.Lock()
+	defer strategiesLock.Unlock()
+	newStrategy, ok := strategies[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown strategy: %v", name)
+	}
+	return newStrategy(), nil
+}
+
+// StrategyNames returns the names of the registered strategies, in registration order.
+func StrategyNames() []string {
+	strategiesLock.Lock()
+	defer strategiesLock.Unlock()
+	return append([]string{}, strategyNames...)
+}
+
+func init() {
+	RegisterStrategy(GreedyStrategy, func() Strategy { return greedy{} })
+	RegisterStrategy(MonteCarloStrategy, func() Strategy { return &monteCarlo{solver: NewSolver()} })
+	RegisterStrategy(MCTSStrategy, func() Strategy { return NewMCTS(DefaultExploration) })
+	RegisterStrategy(ChainStrategy, func() Strategy { return &chainPlayer{solver: NewSolver()} })
+	RegisterStrategy(NimstringStrategy, func() Strategy {
+		return &nimstringPlayer{chainPlayer: chainPlayer{solver: NewSolver()}, nimbers: NewNimberCalculator()}
+	})
+}
+
+// greedy plays the rollout policy directly.
+type greedy struct{}
+
+// BestEdge returns the edge chosen by the rollout policy.
+func (greedy) BestEdge(_ context.Context, p Position, _ Settings) Edge {
+	if p.Board.Size() >= p.EdgesCount() {
+		return InvalidEdge
+	}
+	return getNextEdges(p.Topology, p.Board, rand.New(rand.NewSource(time.Now().UnixNano())))
+}
+
+// monteCarlo plays the flat Monte Carlo search of GetBestEdge.
+type monteCarlo struct {
+	solver *Solver // Endgame solver, used once few edges are left
+}
+
+// BestEdge returns the edge with the best average rollout margin, or the optimal edge in the endgame.
+func (m *monteCarlo) BestEdge(ctx context.Context, p Position, s Settings) Edge {
+	if e, ok := solveEndgame(ctx, m.solver, p, s); ok {
+		return e
+	}
+	return GetBestEdge(ctx, p.Topology, p.Board, s.Goroutines, s.SearchTime)
+}
+
+// RankEdges ranks the edges by their average rollout margin, or by their exact margin in the endgame.
+func (m *monteCarlo) RankEdges(ctx context.Context, p Position, s Settings) []EdgeValue {
+	return rankPlanned(ctx, m.solver, p, s, nil)
+}
+
+// Ponder solves the endgame ahead, since the statistics of the flat search cannot be reused after a move.
+func (m *monteCarlo) Ponder(ctx context.Context, p Position, s Settings) {
+	ponderEndgame(ctx, m.solver, p, s)
+}
+
+// BestEdge runs the tree search with the exploration constant of the settings, or solves the endgame exactly.
+func (m *MCTS) BestEdge(ctx context.Context, p Position, s Settings) Edge {
+	if e, ok := solveEndgame(ctx, &m.solver, p, s); ok {
+		return e
+	}
+	return m.search(ctx, p.Topology, p.Board, s.Goroutines, s.SearchTime, s.Exploration)
+}
+
+// RankEdges ranks the moves of the root by their number of simulations, or by their exact margin in the endgame.
+func (m *MCTS) RankEdges(ctx context.Context, p Position, s Settings) []EdgeValue {
+	if values, ok := rankEndgame(ctx, &m.solver, p, s); ok {
+		return values
+	}
+	if p.Board.Size() >= p.EdgesCount() {
+		return nil
+	}
+	visits, sumScores := m.searchStats(ctx, p.Topology, p.Board, s.Goroutines, s.SearchTime, s.Exploration)
+	var values []EdgeValue
+	for _, e := range p.Edges {
+		if v := visits[e]; v > 0 {
+			values = append(values, EdgeValue{Edge: e, Margin: float64(p.margin()) + sumScores[e]/float64(v)})
+		}
+	}
+	sort.SliceStable(values, func(i, j int) bool { return visits[values[i].Edge] > visits[values[j].Edge] })
+	if len(values) == 0 {
+		values = promote(values, getNextEdges(p.Topology, p.Board, rand.New(rand.NewSource(time.Now().UnixNano()))))
+	}
+	return values
+}
+
+// Ponder grows the search graph from the position of the opponent, so that the node of the position reached by
+// the opponent's move already carries statistics, or solves the endgame ahead.
+func (m *MCTS) Ponder(ctx context.Context, p Position, s Settings) {
+	if ponderEndgame(ctx, &m.solver, p, s) || p.Board.Size() >= p.EdgesCount() {
+		return
+	}
+	m.searchLock.Lock()
+	defer m.searchLock.Unlock()
+	m.grow(ctx, p.Topology, p.Board, s.Goroutines, s.Exploration)
+}
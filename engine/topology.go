@@ -0,0 +1,192 @@
+package engine
+
+// Dot represents a dot on the board.
+type Dot int
+
+// Edge represents an edge between two dots.
+type Edge int
+
+const InvalidEdge Edge = 0 // Constant for invalid edge
+
+// Box represents a box on the board, identified by its top-left dot.
+type Box int
+
+// Topology describes the dots, edges and boxes of a square board of a given size.
+// It is immutable once built and can be shared between any number of games and boards.
+type Topology struct {
+	BoardSize         int             // Number of dots on each side of the board
+	BoardSizePower    Dot             // Power of the board size (used for edge calculations)
+	Dots              []Dot           // All dots on the board
+	Edges             []Edge          // All edges on the board
+	Boxes             []Box           // All boxes on the board
+	EdgeAdjacentBoxes map[Edge][]Box  // Adjacent boxes for each edge
+	EdgesInBox        map[Box][]Edge  // All edges in each box
+	edgeIndex         []int           // Dense index of each edge value, -1 for values that are not edges
+	adjacentBoxes     [][]Box         // Adjacent boxes for each edge index
+	boxEdges          [][]Edge        // All edges in each box, indexed by box value
+	bitBoardLayout    *bitBoardLayout // Precomputed bitset masks shared by every BitBoard
+}
+
+// MaxBoardSize is the largest board size accepted from users and peers. The topology of a board allocates the fourth
+// power of its size, so larger boards would exhaust the memory.
+const MaxBoardSize = 20
+
+// NewTopology builds the topology of a board with the given number of dots on each side.
+func NewTopology(boardSize int) *Topology {
+	t := &Topology{
+		BoardSize:         boardSize,
+		BoardSizePower:    Dot(boardSize * boardSize),
+		EdgeAdjacentBoxes: make(map[Edge][]Box),
+		EdgesInBox:        make(map[Box][]Edge),
+		edgeIndex:         make([]int, boardSize*boardSize*boardSize*boardSize),
+		boxEdges:          make([][]Edge, boardSize*boardSize),
+	}
+	for i := range t.edgeIndex {
+		t.edgeIndex[i] = -1
+	}
+
+	// Initialize dots
+	for i := 0; i < boardSize; i++ {
+		for j := 0; j < boardSize; j++ {
+			t.Dots = append(t.Dots, t.NewDot(i, j))
+		}
+	}
+
+	// Initialize edges
+	for i := 0; i < boardSize; i++ {
+		for j := 0; j < boardSize; j++ {
+			d := t.NewDot(i, j)
+			if i+1 < boardSize {
+				t.addEdge(t.NewEdge(d, t.NewDot(i+1, j)))
+			}
+			if j+1 < boardSize {
+				t.addEdge(t.NewEdge(d, t.NewDot(i, j+1)))
+			}
+		}
+	}
+
+	// Initialize boxes and all edges in each box
+	for _, d := range t.Dots {
+		x, y := t.X(d), t.Y(d)
+		if x >= boardSize-1 || y >= boardSize-1 {
+			continue
+		}
+		b := Box(d)
+		t.Boxes = append(t.Boxes, b)
+		D00 := t.NewDot(x, y)
+		D10 := t.NewDot(x+1, y)
+		D01 := t.NewDot(x, y+1)
+		D11 := t.NewDot(x+1, y+1)
+		t.EdgesInBox[b] = []Edge{
+			t.NewEdge(D00, D01),
+			t.NewEdge(D00, D10),
+			t.NewEdge(D01, D11),
+			t.NewEdge(D10, D11),
+		}
+		t.boxEdges[b] = t.EdgesInBox[b]
+	}
+
+	// Initialize edge-adjacent boxes
+	t.adjacentBoxes = make([][]Box, len(t.Edges))
+	for _, b := range t.Boxes {
+		for _, e := range t.EdgesInBox[b] {
+			t.EdgeAdjacentBoxes[e] = append(t.EdgeAdjacentBoxes[e], b)
+			t.adjacentBoxes[t.edgeIndex[e]] = t.EdgeAdjacentBoxes[e]
+		}
+	}
+	t.bitBoardLayout = newBitBoardLayout(t)
+	return t
+}
+
+// addEdge registers an edge of the board.
+func (t *Topology) addEdge(e Edge) {
+	t.edgeIndex[e] = len(t.Edges)
+	t.Edges = append(t.Edges, e)
+}
+
+// NewDot creates a new dot based on x and y coordinates.
+func (t *Topology) NewDot(x, y int) Dot { return Dot(x*t.BoardSize + y) }
+
+// X returns the x-coordinate of the dot.
+func (t *Topology) X(d Dot) int { return int(d) / t.BoardSize }
+
+// Y returns the y-coordinate of the dot.
+func (t *Topology) Y(d Dot) int { return int(d) % t.BoardSize }
+
+// NewEdge creates a new edge between two dots.
+func (t *Topology) NewEdge(Dot1, Dot2 Dot) Edge { return Edge(Dot1*t.BoardSizePower + Dot2) }
+
+// Dot1 returns the first dot of the edge.
+func (t *Topology) Dot1(e Edge) Dot { return Dot(e) / t.BoardSizePower }
+
+// Dot2 returns the second dot of the edge.
+func (t *Topology) Dot2(e Edge) Dot { return Dot(e) % t.BoardSizePower }
+
+// EdgeIndex returns the position of the edge in Edges, or -1 if it does not belong to the board.
+func (t *Topology) EdgeIndex(e Edge) int {
+	if e < 0 || int(e) >= len(t.edgeIndex) {
+		return -1
+	}
+	return t.edgeIndex[e]
+}
+
+// HasEdge checks if the edge belongs to the board.
+func (t *Topology) HasEdge(e Edge) bool { return t.EdgeIndex(e) >= 0 }
+
+// EdgesCount returns the total number of edges on the board.
+func (t *Topology) EdgesCount() int { return len(t.Edges) }
+
+// AdjacentBoxes returns the boxes adjacent to the edge.
+func (t *Topology) AdjacentBoxes(e Edge) []Box {
+	if i := t.EdgeIndex(e); i >= 0 {
+		return t.adjacentBoxes[i]
+	}
+	return nil
+}
+
+// BoxEdges returns the edges that form the box.
+func (t *Topology) BoxEdges(b Box) []Edge {
+	if b < 0 || int(b) >= len(t.boxEdges) {
+		return nil
+	}
+	return t.boxEdges[b]
+}
+
+// EdgesCountInBox counts how many edges in the specified box are already on the board.
+func (t *Topology) EdgesCountInBox(b Board, box Box) (count int) {
+	if c, ok := b.(boxCounter); ok {
+		return c.EdgesCountInBox(box)
+	}
+	for _, e := range t.BoxEdges(box) {
+		if b.Contains(e) {
+			count++
+		}
+	}
+	return
+}
+
+// ObtainsScore checks how many boxes would be completed by adding an edge.
+func (t *Topology) ObtainsScore(b Board, e Edge) (count int) {
+	if b.Contains(e) {
+		return
+	}
+	for _, box := range t.AdjacentBoxes(e) {
+		if t.EdgesCountInBox(b, box) == 3 {
+			count++
+		}
+	}
+	return
+}
+
+// ObtainsBoxes returns the boxes that would be completed by adding an edge.
+func (t *Topology) ObtainsBoxes(b Board, e Edge) (obtainsBoxes []Box) {
+	if b.Contains(e) {
+		return
+	}
+	for _, box := range t.AdjacentBoxes(e) {
+		if t.EdgesCountInBox(b, box) == 3 {
+			obtainsBoxes = append(obtainsBoxes, box)
+		}
+	}
+	return
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/HuXin0817/dots-and-boxes/engine"
+)
+
+var (
+	HeatmapLowColor     = color.NRGBA{R: 0xFF, G: 0x40, B: 0x40, A: 0xA0} // #FF4040A0, worst edge
+	HeatmapHighColor    = color.NRGBA{R: 0x40, G: 0xC0, B: 0x40, A: 0xA0} // #40C040A0, best edge
+	HeatmapUnknownColor = color.NRGBA{R: 0x80, G: 0x80, B: 0x80, A: 0x60} // #80808060, edge without statistics
+)
+
+// heatmap is the overlay coloring every free edge by its expected final margin.
+type heatmap struct {
+	objects []fyne.CanvasObject // Canvas objects added to the container
+	tooltip *canvas.Text        // Margin of the hovered edge
+}
+
+// heatCell is the overlay of a free edge. It shows the margin of the edge in the tooltip while hovered,
+// and lets taps through to the edge button below since it is not tappable.
+type heatCell struct {
+	widget.BaseWidget
+	rect    *canvas.Rectangle // Colored area over the edge button
+	text    string            // Text shown in the tooltip
+	tooltip *canvas.Text      // Tooltip of the heatmap
+}
+
+// newHeatCell creates the overlay of an edge with the given color and tooltip text.
+func newHeatCell(c color.Color, text string, tooltip *canvas.Text) *heatCell {
+	cell := &heatCell{rect: canvas.NewRectangle(c), text: text, tooltip: tooltip}
+	cell.ExtendBaseWidget(cell)
+	return cell
+}
+
+// CreateRenderer returns the renderer of the cell.
+func (c *heatCell) CreateRenderer() fyne.WidgetRenderer { return widget.NewSimpleRenderer(c.rect) }
+
+// MouseIn shows the margin of the edge next to it.
+func (c *heatCell) MouseIn(*desktop.MouseEvent) {
+	c.tooltip.Text = c.text
+	c.tooltip.Resize(c.tooltip.MinSize())
+	c.tooltip.Move(c.Position().Add(fyne.NewPos(0, -c.tooltip.MinSize().Height)))
+	c.tooltip.Show()
+	c.tooltip.Refresh()
+}
+
+// MouseMoved is required by desktop.Hoverable.
+func (c *heatCell) MouseMoved(*desktop.MouseEvent) {}
+
+// MouseOut hides the tooltip.
+func (c *heatCell) MouseOut() { c.tooltip.Hide() }
+
+// heatColor interpolates between the low and high heatmap colors, x going from 0 to 1.
+func heatColor(x float64) color.Color {
+	lerp := func(a, b uint8) uint8 { return uint8(float64(a) + (float64(b)-float64(a))*x) }
+	return color.NRGBA{
+		R: lerp(HeatmapLowColor.R, HeatmapHighColor.R),
+		G: lerp(HeatmapLowColor.G, HeatmapHighColor.G),
+		B: lerp(HeatmapLowColor.B, HeatmapHighColor.B),
+		A: lerp(HeatmapLowColor.A, HeatmapHighColor.A),
+	}
+}
+
+// ShowHeatmap colors every free edge by its expected final margin from the point of view of the player to move,
+// and draws a legend with the range of the margins.
+func (ui *ui) ShowHeatmap(values []engine.EdgeValue) {
+	ui.HideHeatmap()
+	low, high := math.Inf(1), math.Inf(-1)
+	for _, v := range values {
+		if !math.IsNaN(v.Margin) {
+			low, high = min(low, v.Margin), max(high, v.Margin)
+		}
+	}
+	if low > high {
+		return
+	}
+
+	h := &heatmap{tooltip: canvas.NewText("", theme.Color(theme.ColorNameForeground))}
+	h.tooltip.TextStyle.Bold = true
+	h.tooltip.Hide()
+	for _, v := range values {
+		c, text := color.Color(HeatmapUnknownColor), fmt.Sprintf("%v: not sampled", ui.g.EdgeString(v.Edge))
+		if !math.IsNaN(v.Margin) {
+			x := 1.0
+			if high > low {
+				x = (v.Margin - low) / (high - low)
+			}
+			c, text = heatColor(x), fmt.Sprintf("%v: %+.2f", ui.g.EdgeString(v.Edge), v.Margin)
+		}
+		cell := newHeatCell(c, text, h.tooltip)
+		size, pos := ui.getEdgeButtonSizeAndPosition(v.Edge)
+		cell.Resize(size)
+		cell.Move(pos)
+		h.objects = append(h.objects, cell)
+	}
+
+	// Legend in the top left margin: low margin, gradient, high margin
+	textSize := Chess.BoardMargin / 4
+	lowText := canvas.NewText(fmt.Sprintf("%+.1f", low), theme.Color(theme.ColorNameForeground))
+	highText := canvas.NewText(fmt.Sprintf("%+.1f", high), theme.Color(theme.ColorNameForeground))
+	lowText.TextSize, highText.TextSize = textSize, textSize
+	lowText.Resize(lowText.MinSize())
+	highText.Resize(highText.MinSize())
+	gradient := canvas.NewHorizontalGradient(HeatmapLowColor, HeatmapHighColor)
+	lowText.Move(fyne.NewPos(textSize, textSize/2))
+	gradient.Resize(fyne.NewSize(Chess.DotCanvasDistance, textSize))
+	gradient.Move(fyne.NewPos(textSize+lowText.MinSize().Width+textSize/2, textSize/2+textSize/4))
+	highText.Move(fyne.NewPos(gradient.Position().X+gradient.Size().Width+textSize/2, textSize/2))
+	h.objects = append(h.objects, lowText, gradient, highText, h.tooltip)
+
+	for _, o := range h.objects {
+		Container.Add(o)
+	}
+	ui.heat = h
+}
+
+// HideHeatmap removes the heatmap overlay.
+func (ui *ui) HideHeatmap() {
+	if ui.heat == nil {
+		return
+	}
+	for _, o := range ui.heat.objects {
+		Container.Remove(o)
+	}
+	ui.heat = nil
+}
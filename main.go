@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"image/color"
 	"image/png"
@@ -9,6 +10,7 @@ import (
 	"os"
 	"os/signal"
 	"runtime"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
@@ -17,6 +19,7 @@ import (
 	"fyne.io/fyne/v2/app"
 	"fyne.io/fyne/v2/canvas"
 	"fyne.io/fyne/v2/widget"
+	"github.com/HuXin0817/dots-and-boxes/engine"
 	"github.com/bytedance/sonic"
 	"github.com/gin-gonic/gin"
 )
@@ -27,31 +30,55 @@ const HelpDocUrl = "https://github.com/HuXin0817/dots-and-boxes/blob/main/README
 const (
 	OutputLogFileName              = "output.log"     // File name for storing output logs
 	ChessMetaFileName              = "meta.json"      // File name for storing Chess meta data
+	ControlSocketFileName          = "control.sock"   // File name of the Unix socket of the control protocol
 	DefaultDotDistance             = 80               // Default distance between dots
 	DefaultBoardSize               = 6                // Default board size
 	DefaultStepTime                = time.Second      // Default time for each AI step
 	DefaultPerformanceAnalysisTime = 30 * time.Second // Default time for performance analysis
 	MinDotSize                     = 60               // Minimum size for dots
 	MinBoardSize                   = 1                // Minimum board size
+	MinExploration                 = 0.1              // Minimum UCT exploration constant
+	MinSearchTime                  = time.Millisecond // Minimum AI search time
+	MinSearchGoroutines            = 1                // Minimum number of goroutines of an AI search
+	HintAlternatives               = 3                // Number of alternatives shown next to the hinted edge
 )
 
+// AIEngineMeta stores the strategy and search settings used by an AI player
+type AIEngineMeta struct {
+	Strategy string          `json:"strategy"`          // Name of the registered strategy, or engine.ExternalStrategy
+	Settings engine.Settings `json:"settings"`          // Search settings of the strategy
+	Command  []string        `json:"command,omitempty"` // Command line of the external engine, used by engine.ExternalStrategy
+}
+
 // ChessMeta stores the configuration and state of the game
 type ChessMeta struct {
-	BoardSize               int           `json:"boardSize"`               // Size of the board
-	BoardSizePower          Dot           `json:"boardSizePower"`          // Power of the board size (used for edge calculations)
-	DotCanvasWidth          float32       `json:"dotCanvasWidth"`          // Width of the dot canvas
-	BoardMargin             float32       `json:"boardMargin"`             // Margin of the board
-	BoxCanvasSize           float32       `json:"boxCanvasSize"`           // Size of the box canvas
-	MainWindowSize          float32       `json:"mainWindowSize"`          // Size of the main window
-	DotCanvasDistance       float32       `json:"dotCanvasDistance"`       // Distance between dots
-	AIPlayer1               bool          `json:"aiPlayer1"`               // Flag for AI Player 1
-	AIPlayer2               bool          `json:"aiPlayer2"`               // Flag for AI Player 2
-	AutoRestartGame         bool          `json:"autoRestartGame"`         // Flag for auto-restart game
-	OpenMusic               bool          `json:"openMusic"`               // Flag for opening music
-	AISearchGoroutines      int           `json:"aiSearchGoroutines"`      // Number of goroutines for AI search
-	AISearchTime            time.Duration `json:"aiSearchTime"`            // Time duration for AI search
-	PerformanceAnalysisTime time.Duration `json:"performanceAnalysisTime"` // Time duration for performance analysis
-	ChessMoveRecords        []MoveRecord  `json:"chessMoveRecords"`        // Records of Chess moves
+	BoardSize               int                 `json:"boardSize"`               // Size of the board
+	DotCanvasWidth          float32             `json:"dotCanvasWidth"`          // Width of the dot canvas
+	BoardMargin             float32             `json:"boardMargin"`             // Margin of the board
+	BoxCanvasSize           float32             `json:"boxCanvasSize"`           // Size of the box canvas
+	MainWindowSize          float32             `json:"mainWindowSize"`          // Size of the main window
+	DotCanvasDistance       float32             `json:"dotCanvasDistance"`       // Distance between dots
+	AIPlayer1               bool                `json:"aiPlayer1"`               // Flag for AI Player 1
+	AIPlayer2               bool                `json:"aiPlayer2"`               // Flag for AI Player 2
+	AIPonder                bool                `json:"aiPonder"`                // Flag for AI pondering during the human's turn
+	Heatmap                 bool                `json:"heatmap"`                 // Flag for the evaluation heatmap overlay
+	AutoRestartGame         bool                `json:"autoRestartGame"`         // Flag for auto-restart game
+	OpenMusic               bool                `json:"openMusic"`               // Flag for opening music
+	AIPlayer1Engine         AIEngineMeta        `json:"aiPlayer1Engine"`         // Engine of AI Player 1
+	AIPlayer2Engine         AIEngineMeta        `json:"aiPlayer2Engine"`         // Engine of AI Player 2
+	AISettingsPlayer        engine.Turn         `json:"aiSettingsPlayer"`        // Player whose search settings the menu changes, 0 for both
+	PerformanceAnalysisTime time.Duration       `json:"performanceAnalysisTime"` // Time duration for performance analysis
+	ChessMoveRecords        []engine.MoveRecord `json:"chessMoveRecords"`        // Records of Chess moves
+	MoveTree                *engine.MoveTree    `json:"moveTree"`                // Every line played in the game, with the undone moves
+	MoveTreePanel           bool                `json:"moveTreePanel"`           // Flag for the move tree panel
+	NetworkAddress          string              `json:"networkAddress"`          // Address of the network game last joined
+	HTTPAPI                 bool                `json:"httpApi"`                 // Flag for the HTTP API
+	APIAddress              string              `json:"apiAddress"`              // Address of the HTTP API
+
+	// Search settings of both players saved before the per-player engines, moved into them by migrate
+	AISearchGoroutines int           `json:"aiSearchGoroutines,omitempty"`
+	AISearchTime       time.Duration `json:"aiSearchTime,omitempty"`
+	AISearchAlgorithm  string        `json:"aiSearchAlgorithm,omitempty"`
 }
 
 // NewChessMeta initializes ChessMeta by reading from a file or setting default values.
@@ -61,6 +88,8 @@ func NewChessMeta() *ChessMeta {
 		c := new(ChessMeta)
 		// Unmarshal the JSON data
 		if err := sonic.Unmarshal(b, c); err == nil {
+			c.migrate()
+			c.bound()
 			return c
 		}
 	}
@@ -69,12 +98,83 @@ func NewChessMeta() *ChessMeta {
 		BoardSize:               DefaultBoardSize,
 		DotCanvasDistance:       DefaultDotDistance,
 		OpenMusic:               true,
-		AISearchTime:            DefaultStepTime,
-		AISearchGoroutines:      runtime.NumCPU(),
+		AIPlayer1Engine:         NewAIEngineMeta(),
+		AIPlayer2Engine:         NewAIEngineMeta(),
 		PerformanceAnalysisTime: DefaultPerformanceAnalysisTime,
 	}
 }
 
+// NewAIEngineMeta returns the default engine of an AI player.
+func NewAIEngineMeta() AIEngineMeta {
+	return AIEngineMeta{Strategy: engine.DefaultStrategy, Settings: engine.DefaultSettings()}
+}
+
+// Normalize replaces the unset fields of the engine with their default values.
+func (e *AIEngineMeta) Normalize() {
+	if e.Strategy == "" {
+		e.Strategy = engine.DefaultStrategy
+	}
+	e.Settings.Normalize()
+}
+
+// AIEngine returns the engine of the given player.
+func (chess *ChessMeta) AIEngine(t engine.Turn) *AIEngineMeta {
+	if t == engine.Player1Turn {
+		return &chess.AIPlayer1Engine
+	}
+	return &chess.AIPlayer2Engine
+}
+
+// migrate moves the search settings of both players saved by older versions into the engine of each player.
+func (chess *ChessMeta) migrate() {
+	for _, e := range []*AIEngineMeta{&chess.AIPlayer1Engine, &chess.AIPlayer2Engine} {
+		if chess.AISearchGoroutines != 0 {
+			e.Settings.Goroutines = chess.AISearchGoroutines
+		}
+		if chess.AISearchTime != 0 {
+			e.Settings.SearchTime = chess.AISearchTime
+		}
+		if chess.AISearchAlgorithm != "" {
+			e.Strategy = chess.AISearchAlgorithm
+		}
+	}
+	chess.AISearchGoroutines, chess.AISearchTime, chess.AISearchAlgorithm = 0, 0, ""
+}
+
+// AISettingsPlayers returns the players whose search settings the menu changes.
+func (chess *ChessMeta) AISettingsPlayers() []engine.Turn {
+	switch chess.AISettingsPlayer {
+	case engine.Player1Turn, engine.Player2Turn:
+		return []engine.Turn{chess.AISettingsPlayer}
+	default:
+		return []engine.Turn{engine.Player1Turn, engine.Player2Turn}
+	}
+}
+
+// bound drops the board sizes of the meta file that the game cannot be played on, with the moves played on them.
+// An unset board size is left to the default set at startup.
+func (chess *ChessMeta) bound() {
+	if chess.BoardSize != 0 && (chess.BoardSize < MinBoardSize || chess.BoardSize > engine.MaxBoardSize) {
+		log.Printf("invalid board size %v in %v\n", chess.BoardSize, ChessMetaFileName)
+		chess.BoardSize, chess.ChessMoveRecords = DefaultBoardSize, nil
+	}
+	if chess.MoveTree != nil && (chess.MoveTree.BoardSize < MinBoardSize || chess.MoveTree.BoardSize > engine.MaxBoardSize) {
+		log.Printf("invalid board size %v of the move tree in %v\n", chess.MoveTree.BoardSize, ChessMetaFileName)
+		chess.MoveTree = nil
+	}
+}
+
+// UpdateAISettings applies f to the search settings of the given players, then keeps each of them within its limits.
+func (chess *ChessMeta) UpdateAISettings(players []engine.Turn, f func(s *engine.Settings)) {
+	for _, t := range players {
+		s := &chess.AIEngine(t).Settings
+		f(s)
+		s.Goroutines = max(s.Goroutines, MinSearchGoroutines)
+		s.SearchTime = max(s.SearchTime, MinSearchTime)
+		s.Exploration = max(s.Exploration, MinExploration)
+	}
+}
+
 func (chess *ChessMeta) Refresh() error {
 	j, err := sonic.Marshal(chess)
 	if err != nil {
@@ -88,26 +188,17 @@ func (chess *ChessMeta) Refresh() error {
 
 // Global variables and initialization
 var (
-	Message           = NewMessageManager()                   // Initialize MessageManager
-	Chess             = NewChessMeta()                        // Initialize Chess meta data
-	Player1Score      int                                     // Score of Player 1
-	Player2Score      int                                     // Score of Player 2
-	CurrentBoard      Board                                   // Current state of the board
-	CurrentTurn       Turn                                    // Current turn of the game
-	AllEdgesCount     int                                     // Total number of edges
-	AllDots           []Dot                                   // All dots on the board
-	AllBoxes          []Box                                   // All boxes on the board
-	AllEdges          map[Edge]struct{}                       // All edges on the board
-	EdgeAdjacentBoxes map[Edge][]Box                          // Adjacent boxes for each edge
-	AllEdgesInBox     map[Box][]Edge                          // All edges in each box
-	SignChan          = make(chan struct{}, 1)                // Channel for signaling AI moves
-	MainWindow        = app.New().NewWindow("Dots and Boxes") // Main window of the application
-	Container         *fyne.Container                         // Container for holding UI elements
-	DotCanvases       map[Dot]*canvas.Circle                  // Canvases for dots
-	EdgesCanvases     map[Edge]*canvas.Line                   // Canvases for edges
-	BoxesCanvases     map[Box]*canvas.Rectangle               // Canvases for boxes
-	EdgeButtons       map[Edge]*widget.Button                 // Buttons for edges
-	BoxesFilledColor  map[Box]color.Color                     // Colors for filled boxes
+	Message          = NewMessageManager()                   // Initialize MessageManager
+	Chess            = NewChessMeta()                        // Initialize Chess meta data
+	AIStrategies     = make(map[engine.Turn]*AIStrategy)     // Strategy instances of the AI players
+	Nimbers          = engine.NewNimberCalculator()          // Nimstring values shown by the board analysis
+	MainWindow       = app.New().NewWindow("Dots and Boxes") // Main window of the application
+	Container        *fyne.Container                         // Container for holding UI elements
+	DotCanvases      map[engine.Dot]*canvas.Circle           // Canvases for dots
+	EdgesCanvases    map[engine.Edge]*canvas.Line            // Canvases for edges
+	BoxesCanvases    map[engine.Box]*canvas.Rectangle        // Canvases for boxes
+	EdgeButtons      map[engine.Edge]*widget.Button          // Buttons for edges
+	BoxesFilledColor map[engine.Box]color.Color              // Colors for filled boxes
 
 	globalLock      sync.Mutex // Global mutex for synchronization
 	boxesCanvasLock sync.Mutex // Mutex for box canvas synchronization
@@ -115,117 +206,6 @@ var (
 	RefreshMacOSIcon = func([]byte) {} // Function for refreshing macOS icon
 )
 
-// Turn represents the current player's turn
-type Turn int
-
-const (
-	Player1Turn Turn = 1            // Constant for Player 1's turn
-	Player2Turn      = -Player1Turn // Constant for Player 2's turn
-)
-
-// String returns the string representation of the current turn.
-func (t Turn) String() string {
-	if t == Player1Turn {
-		return "Player1"
-	} else {
-		return "Player2"
-	}
-}
-
-// ChangeTurn switches the current turn to the other player.
-func ChangeTurn(t *Turn) { *t = -*t }
-
-// Dot represents a dot on the board.
-type Dot int
-
-// NewDot creates a new dot based on x and y coordinates.
-func NewDot(x, y int) Dot { return Dot(x*Chess.BoardSize + y) }
-
-// X returns the x-coordinate of the dot.
-func (d Dot) X() int { return int(d) / Chess.BoardSize }
-
-// Y returns the y-coordinate of the dot.
-func (d Dot) Y() int { return int(d) % Chess.BoardSize }
-
-// String returns the string representation of the dot.
-func (d Dot) String() string { return fmt.Sprintf("(%v, %v)", d.X(), d.Y()) }
-
-// Edge represents an edge between two dots.
-type Edge int
-
-const InvalidEdge Edge = 0 // Constant for invalid edge
-
-// NewEdge creates a new edge between two dots.
-func NewEdge(Dot1, Dot2 Dot) Edge { return Edge(Dot1*Chess.BoardSizePower + Dot2) }
-
-// Dot1 returns the first dot of the edge.
-func (e Edge) Dot1() Dot { return Dot(e) / Chess.BoardSizePower }
-
-// Dot2 returns the second dot of the edge.
-func (e Edge) Dot2() Dot { return Dot(e) % Chess.BoardSizePower }
-
-// String returns the string representation of the edge.
-func (e Edge) String() string { return fmt.Sprintf("%v => %v", e.Dot1(), e.Dot2()) }
-
-// AdjacentBoxes returns the boxes adjacent to the edge.
-func (e Edge) AdjacentBoxes() []Box { return EdgeAdjacentBoxes[e] }
-
-// Box represents a box on the board.
-type Box int
-
-// Edges returns the edges that form the box.
-func (b Box) Edges() []Edge { return AllEdgesInBox[b] }
-
-// Board interface defines the methods for managing the board state.
-type Board interface {
-	Add(e Edge)           // Adds an edge to the board
-	Contains(e Edge) bool // Checks if an edge is on the board
-	Clone() Board         // Clones the board
-	Size() int            // Returns the size of the board
-}
-
-// board is a concrete implementation of the Board interface.
-type board map[Edge]struct{}
-
-// NewBoard creates a new, empty board.
-func NewBoard() Board { return make(board) }
-
-// Add adds an edge to the board.
-func (b board) Add(e Edge) { b[e] = struct{}{} }
-
-// Contains checks if an edge is on the board.
-func (b board) Contains(e Edge) bool {
-	_, ok := b[e]
-	return ok
-}
-
-// Size returns the number of edges on the board.
-func (b board) Size() int { return len(b) }
-
-// Clone creates a copy of the board.
-func (b board) Clone() Board {
-	cb := make(board, b.Size())
-	for e := range b {
-		cb.Add(e)
-	}
-	return &cb
-}
-
-// MoveRecord records a move in the game.
-type MoveRecord struct {
-	TimeStamp    time.Time `json:"timeStamp"`    // The timestamp of the move
-	Step         int       `json:"step"`         // The step number of the move
-	Player       Turn      `json:"player"`       // The player who made the move
-	MoveEdge     Edge      `json:"moveEdge"`     // The edge that was moved
-	Player1Score int       `json:"player1Score"` // The score of Player 1 after the move
-	Player2Score int       `json:"player2Score"` // The score of Player 2 after the move
-}
-
-// String returns the string representation of the move record.
-func (m MoveRecord) String() string {
-	return fmt.Sprintf("%v Step: %v, Turn: %v, Edge: %v, Player1Score: %v, Player2Score: %v", m.TimeStamp.Format(time.DateTime), m.Step, m.Player, m.MoveEdge, m.Player1Score, m.Player2Score)
-}
-
 func GetMessage(head string, value bool) string {
 	if value {
 		return head + " ON"
@@ -263,148 +243,90 @@ func (m *MessageManager) Send(format string, a ...any) {
 	}
 }
 
-// EdgesCountInBox counts how many edges in the specified box are already on the board.
-func EdgesCountInBox(b Board, box Box) (count int) {
-	boxEdges := box.Edges()
-	for _, e := range boxEdges {
-		if b.Contains(e) {
-			count++
-		}
-	}
-	return
+// AIStrategy is the strategy instance of an AI player, kept between moves so that stateful engines reuse their search.
+type AIStrategy struct {
+	engine.Strategy        // Strategy instance, nil while the external engine is starting or if it failed to start
+	Name            string // Name of the registered strategy
+	Command         string // Command line of the external engine
+	Err             error  // Why the external engine failed to start
 }
 
-// ObtainsScore checks how many boxes would be completed by adding an edge.
-func ObtainsScore(b Board, e Edge) (count int) {
-	if b.Contains(e) {
-		return
-	}
-	boxes := e.AdjacentBoxes()
-	for _, box := range boxes {
-		if EdgesCountInBox(b, box) == 3 {
-			count++
+// ErrEngineStarting is returned by GetStrategy while the external engine of the player is starting.
+var ErrEngineStarting = errors.New("the external engine is starting")
+
+// GetStrategy returns the strategy instance of the player, creating a new one when the configured strategy changed.
+// The external engine of the previous instance, if any, is stopped. An external engine is started in the background,
+// see startExternalEngine, and its failure to start is kept until the configured strategy changes.
+// It must be called with globalLock held.
+func GetStrategy(t engine.Turn) (engine.Strategy, error) {
+	name, command := Chess.AIEngine(t).Strategy, Chess.AIEngine(t).Command
+	if s, ok := AIStrategies[t]; ok && s.Name == name && s.Command == strings.Join(command, " ") {
+		switch {
+		case s.Err != nil:
+			return nil, s.Err
+		case s.Strategy == nil:
+			return nil, ErrEngineStarting
 		}
+		return s.Strategy, nil
 	}
-	return
-}
-
-// ObtainsBoxes returns the boxes that would be completed by adding an edge.
-func ObtainsBoxes(b Board, e Edge) (obtainsBoxes []Box) {
-	if b.Contains(e) {
-		return
+	if s, ok := AIStrategies[t]; ok {
+		if x, ok := s.Strategy.(*engine.ExternalEngine); ok {
+			go x.Close()
+		}
+		delete(AIStrategies, t)
 	}
-	boxes := e.AdjacentBoxes()
-	for _, box := range boxes {
-		if EdgesCountInBox(b, box) == 3 {
-			obtainsBoxes = append(obtainsBoxes, box)
+	if name == engine.ExternalStrategy {
+		if len(command) == 0 {
+			return nil, fmt.Errorf("no command of the %v engine of %v in %v", name, t, ChessMetaFileName)
 		}
+		s := &AIStrategy{Name: name, Command: strings.Join(command, " ")}
+		AIStrategies[t] = s
+		go startExternalEngine(t, s, command)
+		return nil, ErrEngineStarting
 	}
-	return
+	s, err := engine.NewStrategy(name)
+	if err != nil {
+		return nil, err
+	}
+	AIStrategies[t] = &AIStrategy{Strategy: s, Name: name}
+	return s, nil
 }
 
-// getNextEdges evaluates and selects the next best edge to draw on the board.
-// It returns the edge that either immediately obtains a score or minimizes the opponent's potential score.
-func getNextEdges(b Board) (bestEdge Edge) {
-	enemyMinScore := 3
-	for e := range AllEdges {
-		// Check if the edge is not a part of the board
-		if !b.Contains(e) {
-			// Check if drawing this edge obtains a score
-			if score := ObtainsScore(b, e); score > 0 {
-				return e // Immediately return if it obtains a score
-			} else if score == 0 {
-				// Evaluate the potential score the opponent might gain
-				boxes := e.AdjacentBoxes()
-				enemyScore := 0
-				for _, box := range boxes {
-					if EdgesCountInBox(b, box) == 2 {
-						enemyScore++ // Increment if the opponent could score here
-					}
-				}
-				// Select the edge that minimizes the appointment's Engine potential score
-				if enemyMinScore > enemyScore {
-					enemyMinScore = enemyScore
-					bestEdge = e
-				}
-			}
+// startExternalEngine starts the external engine of instance s of the player without holding globalLock,
+// since the engine may take up to engine.ExternalEngineTimeout to identify itself, then wakes the scheduler up.
+// The engine is stopped if the player changed engine in the meantime.
+func startExternalEngine(t engine.Turn, s *AIStrategy, command []string) {
+	x, err := engine.NewExternalEngine(command[0], command[1:]...)
+	globalLock.Lock()
+	defer globalLock.Unlock()
+	if AIStrategies[t] != s {
+		if err == nil {
+			go x.Close()
 		}
+		return
 	}
-	return
-}
-
-// GetBestEdge performs a multithreaded search to determine the best edge to draw.
-// It uses multiple goroutines to simulate the game and gather statistics on edge performance.
-func GetBestEdge() (bestEdge Edge) {
-	// Maps to store global search times and scores for each edge
-	globalSearchTime := make(map[Edge]int)
-	globalSumScore := make(map[Edge]int)
-	// Slice of maps to store local search times and scores for each goroutine
-	localSearchTimes := make([]map[Edge]int, Chess.AISearchGoroutines)
-	localSumScores := make([]map[Edge]int, Chess.AISearchGoroutines)
-	var wg sync.WaitGroup
-	wg.Add(Chess.AISearchGoroutines)
-	// Launch multiple goroutines for parallel edge evaluation
-	for i := 0; i < Chess.AISearchGoroutines; i++ {
-		localSearchTime := make(map[Edge]int)
-		localSumScore := make(map[Edge]int)
-		localSearchTimes[i] = localSearchTime
-		localSumScores[i] = localSumScore
-		go func() {
-			defer wg.Done()
-			// Set a timeout for each goroutine to limit search time
-			timer := time.NewTimer(Chess.AISearchTime)
-			for {
-				select {
-				case <-timer.C:
-					return // Exit when the context times out
-				default:
-					// Clone the current board state
-					b := CurrentBoard.Clone()
-					firstEdge := InvalidEdge
-					score := 0
-					turn := Player1Turn
-					// Simulate the game until all edges are drawn
-					for b.Size() < AllEdgesCount {
-						edge := getNextEdges(b)
-						if firstEdge == InvalidEdge {
-							firstEdge = edge
-						}
-						s := ObtainsScore(b, edge)
-						score += int(turn) * s
-						if s == 0 {
-							ChangeTurn(&turn)
-						}
-						b.Add(edge)
-					}
-					// Update local statistics for the first edge chosen
-					localSearchTime[firstEdge]++
-					localSumScore[firstEdge] += score
-				}
-			}
-		}()
+	if err != nil {
+		s.Err = err
+	} else {
+		s.Strategy = x
 	}
-	wg.Wait() // Wait for all goroutines to finish
+	Scheduler.signal(EngineEvent)
+}
 
-	// Aggregate local statistics into global statistics
-	for i := range Chess.AISearchGoroutines {
-		for e, s := range localSearchTimes[i] {
-			globalSearchTime[e] += s
-		}
-		for e, s := range localSumScores[i] {
-			globalSumScore[e] += s
-		}
+// GetAIStrategy returns the strategy instance and search settings of the player,
+// falling back to the default strategy if the configured one is not registered or failed to start.
+// The strategy is nil while the external engine of the player is starting.
+func GetAIStrategy(t engine.Turn) (engine.Strategy, engine.Settings) {
+	s, err := GetStrategy(t)
+	if errors.Is(err, ErrEngineStarting) {
+		return nil, Chess.AIEngine(t).Settings
 	}
-
-	// Determine the best edge based on the highest average score
-	bestScore := -1e9
-	for e, score := range globalSumScore {
-		averageScore := float64(score) / float64(globalSearchTime[e])
-		if averageScore > bestScore {
-			bestEdge = e
-			bestScore = averageScore
-		}
+	if err != nil {
+		Message.Send(err.Error())
+		Chess.AIEngine(t).Strategy = engine.DefaultStrategy
+		s, _ = GetStrategy(t)
 	}
-	return
+	return s, Chess.AIEngine(t).Settings
 }
 
 // the main is the entry point of the application.
@@ -423,7 +345,7 @@ func main() {
 	}()
 
 	globalLock.Lock()
-	MoveRecords := append([]MoveRecord{}, Chess.ChessMoveRecords...)
+	MoveRecords := append([]engine.MoveRecord{}, Chess.ChessMoveRecords...)
 	MainWindow.SetFixedSize(true)
 	fyne.CurrentApp().Settings().SetTheme(gameTheme)
 	fyne.CurrentApp().Lifecycle().SetOnStopped(game.Refresh)
@@ -437,8 +359,13 @@ func main() {
 		if Chess.BoardSize == 0 {
 			Chess.BoardSize = DefaultBoardSize
 		}
+		if Chess.APIAddress == "" {
+			Chess.APIAddress = DefaultAPIAddress
+		}
+		Chess.AIPlayer1Engine.Normalize()
+		Chess.AIPlayer2Engine.Normalize()
 		game.SetDotDistance(Chess.DotCanvasDistance)
-		if len(MoveRecords) > 0 {
+		if len(MoveRecords) > 0 || Chess.MoveTree != nil {
 			game.Recover(MoveRecords)
 		} else {
 			game.Restart(Chess.BoardSize)
@@ -462,15 +389,16 @@ func main() {
 			}
 		}()
 
-		// Handle AI move signals.
-		go func() {
-			for range SignChan {
-				globalLock.Lock()
-				game.AddEdge(GetBestEdge())
-				game.Refresh()
-				globalLock.Unlock()
-			}
-		}()
+		// Play the moves of the AI players.
+		go Scheduler.Run(game)
+
+		// Let other programs drive the game.
+		go ServeControl(ControlSocketFileName)
+		if Chess.HTTPAPI {
+			globalLock.Lock()
+			StartAPI(Chess.APIAddress)
+			globalLock.Unlock()
+		}
 	}()
 
 	MainWindow.ShowAndRun()
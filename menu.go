@@ -16,7 +16,10 @@ import (
 	"time"
 
 	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/widget"
+	"github.com/HuXin0817/dots-and-boxes/engine"
 	ginpprof "github.com/gin-contrib/pprof"
 	"github.com/gin-gonic/gin"
 )
@@ -26,11 +29,14 @@ var (
 	MusicMenuItem                           *fyne.MenuItem
 	AIPlayer1MenuItem                       *fyne.MenuItem
 	AIPlayer2MenuItem                       *fyne.MenuItem
+	PonderMenuItem                          *fyne.MenuItem
 	AutoRestartMenuItem                     *fyne.MenuItem
 	IncreaseBoardSizeMenuItem               *fyne.MenuItem
 	ReduceBoardSizeMenuItem                 *fyne.MenuItem
 	ResetBoardSizeMenuItem                  *fyne.MenuItem
 	UndoMenuItem                            *fyne.MenuItem
+	RedoMenuItem                            *fyne.MenuItem
+	MoveTreeMenuItem                        *fyne.MenuItem
 	IncreaseBoardWidthMenuItem              *fyne.MenuItem
 	ReduceBoardWidthMenuItem                *fyne.MenuItem
 	ResetBoardWidthMenuItem                 *fyne.MenuItem
@@ -38,12 +44,32 @@ var (
 	ReduceSearchGoroutinesMenuItem          *fyne.MenuItem
 	ResetSearchGoroutinesMenuItem           *fyne.MenuItem
 	ScoreMenuItem                           *fyne.MenuItem
+	AnalysisMenuItem                        *fyne.MenuItem
+	HintMenuItem                            *fyne.MenuItem
+	HeatmapMenuItem                         *fyne.MenuItem
+	ReviewMenuItem                          *fyne.MenuItem
+	ChartMenuItem                           *fyne.MenuItem
+	CopyPositionMenuItem                    *fyne.MenuItem
+	OpenGameMenuItem                        *fyne.MenuItem
 	SaveScreenshotMenuItem                  *fyne.MenuItem
+	HostNetworkGameMenuItem                 *fyne.MenuItem
+	JoinNetworkGameMenuItem                 *fyne.MenuItem
+	HTTPAPIMenuItem                         *fyne.MenuItem
+	APIAddressMenuItem                      *fyne.MenuItem
 	QuitMenuItem                            *fyne.MenuItem
 	HelpMenuItem                            *fyne.MenuItem
 	IncreaseAISearchTimeMenuItem            *fyne.MenuItem
 	ReduceAISearchTimeMenuItem              *fyne.MenuItem
 	ResetAISearchTimeMenuItem               *fyne.MenuItem
+	AIPlayer1StrategyMenuItem               *fyne.MenuItem
+	AIPlayer2StrategyMenuItem               *fyne.MenuItem
+	AISettingsPlayerMenuItem                *fyne.MenuItem
+	IncreaseAIExplorationMenuItem           *fyne.MenuItem
+	ReduceAIExplorationMenuItem             *fyne.MenuItem
+	ResetAIExplorationMenuItem              *fyne.MenuItem
+	IncreaseSolverThresholdMenuItem         *fyne.MenuItem
+	ReduceSolverThresholdMenuItem           *fyne.MenuItem
+	ResetSolverThresholdMenuItem            *fyne.MenuItem
 	SavePerformanceAnalysisMenuItem         *fyne.MenuItem
 	IncreasePerformanceAnalysisTimeMenuItem *fyne.MenuItem
 	ReducePerformanceAnalysisTimeMenuItem   *fyne.MenuItem
@@ -81,6 +107,37 @@ func getSaveFilePath() (string, error) {
 	return strings.TrimSpace(string(output)), nil
 }
 
+// getOpenFilePath returns the path of the game log selected by the user.
+func getOpenFilePath() (string, error) {
+	var script string
+
+	switch runtime.GOOS {
+	case "darwin":
+		script = `osascript -e 'set myFile to choose file with prompt "Open game log:"' -e 'POSIX path of myFile'`
+	case "windows":
+		script = `Add-Type -AssemblyName System.Windows.Forms; $file = New-Object System.Windows.Forms.OpenFileDialog; $file.Filter = "Game Logs|*.log;*.json"; if($file.ShowDialog() -eq 'OK') {$file.FileName}`
+	case "linux":
+		script = `zenity --file-selection --file-filter="Game Logs | *.log *.json"`
+	default:
+		return "", fmt.Errorf("unsupported platform")
+	}
+
+	var cmd *exec.Cmd
+
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", script)
+	} else {
+		cmd = exec.Command("sh", "-c", script)
+	}
+
+	output, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(output)), nil
+}
+
 func init() {
 	RestartGameMenuItem = &fyne.MenuItem{
 		Action: func() {
@@ -94,12 +151,109 @@ func init() {
 
 	ScoreMenuItem = &fyne.MenuItem{
 		Action: func() {
-			message := fmt.Sprintf("Player1 Score: %v\nPlayer2 Score: %v\n", Player1Score, Player2Score)
+			message := fmt.Sprintf("Player1 Score: %v\nPlayer2 Score: %v\n", game.Game().Player1Score(), game.Game().Player2Score())
 			Message.Send(message)
 		},
 		Shortcut: &desktop.CustomShortcut{KeyName: fyne.KeyT},
 	}
 
+	AnalysisMenuItem = &fyne.MenuItem{
+		Action: func() {
+			globalLock.Lock()
+			defer globalLock.Unlock()
+			g := game.Game()
+			a := engine.Analyze(g.Position())
+			nimber := "?"
+			if v, ok := Nimbers.Nimber(g.Topology, g.Board()); ok {
+				nimber = fmt.Sprintf("*%v", v)
+			}
+			Message.Send("%v\n%v\nNimber: %v, Components: %v", a, a.Chains, nimber, Nimbers.Components(g.Topology, g.Board()))
+		},
+		Shortcut: &desktop.CustomShortcut{KeyName: fyne.KeyC},
+	}
+
+	HintMenuItem = &fyne.MenuItem{
+		Action: func() {
+			globalLock.Lock()
+			defer globalLock.Unlock()
+			if game.Game().Over() || IsAIPlayer(game.Game().Turn()) {
+				Message.Send("Hints are only given to a human player to move")
+				return
+			}
+			Message.Send("Searching Hint for %v", game.Game().Turn())
+			Scheduler.RequestHint()
+		},
+		Shortcut: &desktop.CustomShortcut{KeyName: fyne.KeyI},
+	}
+
+	HeatmapMenuItem = &fyne.MenuItem{
+		Action: func() {
+			globalLock.Lock()
+			defer globalLock.Unlock()
+			defer game.Refresh()
+			Message.Send(GetMessage("Heatmap", !Chess.Heatmap))
+			Chess.Heatmap = !Chess.Heatmap
+			if !Chess.Heatmap {
+				game.HideHeatmap()
+			}
+			Scheduler.Notify(ToggleEvent)
+		},
+		Shortcut: &desktop.CustomShortcut{KeyName: fyne.KeyE},
+	}
+
+	ReviewMenuItem = &fyne.MenuItem{
+		Action: func() {
+			globalLock.Lock()
+			defer globalLock.Unlock()
+			defer game.Refresh()
+			if !game.Game().Over() {
+				Message.Send("Only finished games can be reviewed")
+				return
+			}
+			ReviewGame(game.Game().BoardSize, game.Game().Records())
+		},
+		Shortcut: &desktop.CustomShortcut{KeyName: fyne.KeyV},
+	}
+
+	ChartMenuItem = &fyne.MenuItem{
+		Action: func() {
+			globalLock.Lock()
+			defer globalLock.Unlock()
+			if game.Game().Step() == 0 {
+				return
+			}
+			ShowChart(game.Game().BoardSize, game.Game().Records())
+		},
+		Shortcut: &desktop.CustomShortcut{KeyName: fyne.KeyG},
+	}
+
+	CopyPositionMenuItem = &fyne.MenuItem{
+		Action: func() {
+			globalLock.Lock()
+			defer globalLock.Unlock()
+			position := game.Game().Position().String()
+			MainWindow.Clipboard().SetContent(position)
+			Message.Send("Position Copied: %v", position)
+		},
+		Shortcut: &desktop.CustomShortcut{KeyName: fyne.KeyK},
+	}
+
+	OpenGameMenuItem = &fyne.MenuItem{
+		Action: func() {
+			go func() {
+				path, err := getOpenFilePath()
+				if err != nil {
+					Message.Send(err.Error())
+					return
+				}
+				if path != "" {
+					OpenGame(path)
+				}
+			}()
+		},
+		Shortcut: &desktop.CustomShortcut{KeyName: fyne.KeyL},
+	}
+
 	IncreaseBoardSizeMenuItem = &fyne.MenuItem{
 		Action: func() {
 			globalLock.Lock()
@@ -139,6 +293,28 @@ func init() {
 		Shortcut: &desktop.CustomShortcut{KeyName: fyne.KeyZ},
 	}
 
+	RedoMenuItem = &fyne.MenuItem{
+		Action: func() {
+			globalLock.Lock()
+			defer globalLock.Unlock()
+			defer game.Refresh()
+			game.Redo()
+		},
+		Shortcut: &desktop.CustomShortcut{KeyName: fyne.KeyZ, Modifier: fyne.KeyModifierShift},
+	}
+
+	MoveTreeMenuItem = &fyne.MenuItem{
+		Action: func() {
+			globalLock.Lock()
+			defer globalLock.Unlock()
+			defer game.Refresh()
+			Message.Send(GetMessage("Move Tree", !Chess.MoveTreePanel))
+			Chess.MoveTreePanel = !Chess.MoveTreePanel
+			game.Recover(game.Game().Records())
+		},
+		Shortcut: &desktop.CustomShortcut{KeyName: fyne.KeyM},
+	}
+
 	SaveScreenshotMenuItem = &fyne.MenuItem{
 		Action: func() {
 			globalLock.Lock()
@@ -215,13 +391,25 @@ func init() {
 		Shortcut: &desktop.CustomShortcut{KeyName: fyne.Key2},
 	}
 
+	PonderMenuItem = &fyne.MenuItem{
+		Action: func() {
+			globalLock.Lock()
+			defer globalLock.Unlock()
+			defer game.Refresh()
+			Message.Send(GetMessage("AI Pondering", !Chess.AIPonder))
+			Chess.AIPonder = !Chess.AIPonder
+			Scheduler.Notify(ToggleEvent)
+		},
+		Shortcut: &desktop.CustomShortcut{KeyName: fyne.KeyO},
+	}
+
 	IncreaseAISearchTimeMenuItem = &fyne.MenuItem{
 		Action: func() {
 			globalLock.Lock()
 			defer globalLock.Unlock()
 			defer game.Refresh()
-			Chess.AISearchTime = Chess.AISearchTime << 1
-			Message.Send("Now AISearchTime: %v", Chess.AISearchTime)
+			Chess.UpdateAISettings(Chess.AISettingsPlayers(), func(s *engine.Settings) { s.SearchTime <<= 1 })
+			Message.Send("Now AISearchTime: Player1 %v, Player2 %v", Chess.AIPlayer1Engine.Settings.SearchTime, Chess.AIPlayer2Engine.Settings.SearchTime)
 		},
 		Shortcut: &desktop.CustomShortcut{KeyName: fyne.Key3},
 	}
@@ -231,8 +419,8 @@ func init() {
 			globalLock.Lock()
 			defer globalLock.Unlock()
 			defer game.Refresh()
-			Chess.AISearchTime = Chess.AISearchTime >> 1
-			Message.Send("Now AISearchTime: %v", Chess.AISearchTime)
+			Chess.UpdateAISettings(Chess.AISettingsPlayers(), func(s *engine.Settings) { s.SearchTime >>= 1 })
+			Message.Send("Now AISearchTime: Player1 %v, Player2 %v", Chess.AIPlayer1Engine.Settings.SearchTime, Chess.AIPlayer2Engine.Settings.SearchTime)
 		},
 		Shortcut: &desktop.CustomShortcut{KeyName: fyne.Key4},
 	}
@@ -242,19 +430,89 @@ func init() {
 			globalLock.Lock()
 			defer globalLock.Unlock()
 			defer game.Refresh()
-			Chess.AISearchTime = time.Second
-			Message.Send("Now AISearchTime: %v", Chess.AISearchTime)
+			Chess.UpdateAISettings(Chess.AISettingsPlayers(), func(s *engine.Settings) { s.SearchTime = DefaultStepTime })
+			Message.Send("Now AISearchTime: Player1 %v, Player2 %v", Chess.AIPlayer1Engine.Settings.SearchTime, Chess.AIPlayer2Engine.Settings.SearchTime)
 		},
 		Shortcut: &desktop.CustomShortcut{KeyName: fyne.Key5},
 	}
 
+	AIPlayer1StrategyMenuItem = &fyne.MenuItem{ChildMenu: newStrategyMenu(engine.Player1Turn)}
+
+	AIPlayer2StrategyMenuItem = &fyne.MenuItem{ChildMenu: newStrategyMenu(engine.Player2Turn)}
+
+	AISettingsPlayerMenuItem = &fyne.MenuItem{ChildMenu: newSettingsPlayerMenu()}
+
+	IncreaseAIExplorationMenuItem = &fyne.MenuItem{
+		Action: func() {
+			globalLock.Lock()
+			defer globalLock.Unlock()
+			defer game.Refresh()
+			Chess.UpdateAISettings(Chess.AISettingsPlayers(), func(s *engine.Settings) { s.Exploration += 0.1 })
+			Message.Send("Now AIExploration: Player1 %.1f, Player2 %.1f", Chess.AIPlayer1Engine.Settings.Exploration, Chess.AIPlayer2Engine.Settings.Exploration)
+		},
+	}
+
+	ReduceAIExplorationMenuItem = &fyne.MenuItem{
+		Action: func() {
+			globalLock.Lock()
+			defer globalLock.Unlock()
+			defer game.Refresh()
+			Chess.UpdateAISettings(Chess.AISettingsPlayers(), func(s *engine.Settings) { s.Exploration -= 0.1 })
+			Message.Send("Now AIExploration: Player1 %.1f, Player2 %.1f", Chess.AIPlayer1Engine.Settings.Exploration, Chess.AIPlayer2Engine.Settings.Exploration)
+		},
+	}
+
+	ResetAIExplorationMenuItem = &fyne.MenuItem{
+		Action: func() {
+			globalLock.Lock()
+			defer globalLock.Unlock()
+			defer game.Refresh()
+			Chess.UpdateAISettings(Chess.AISettingsPlayers(), func(s *engine.Settings) { s.Exploration = engine.DefaultExploration })
+			Message.Send("Now AIExploration: Player1 %.1f, Player2 %.1f", Chess.AIPlayer1Engine.Settings.Exploration, Chess.AIPlayer2Engine.Settings.Exploration)
+		},
+	}
+
+	IncreaseSolverThresholdMenuItem = &fyne.MenuItem{
+		Action: func() {
+			globalLock.Lock()
+			defer globalLock.Unlock()
+			defer game.Refresh()
+			Chess.UpdateAISettings(Chess.AISettingsPlayers(), func(s *engine.Settings) { s.SolverThreshold = max(s.SolverThreshold, 0) + 2 })
+			Message.Send("Now SolverThreshold: Player1 %v, Player2 %v", Chess.AIPlayer1Engine.Settings.SolverThreshold, Chess.AIPlayer2Engine.Settings.SolverThreshold)
+		},
+	}
+
+	ReduceSolverThresholdMenuItem = &fyne.MenuItem{
+		Action: func() {
+			globalLock.Lock()
+			defer globalLock.Unlock()
+			defer game.Refresh()
+			Chess.UpdateAISettings(Chess.AISettingsPlayers(), func(s *engine.Settings) {
+				if s.SolverThreshold -= 2; s.SolverThreshold <= 0 {
+					s.SolverThreshold = engine.SolverDisabled // 0 would be read back as the default threshold
+				}
+			})
+			Message.Send("Now SolverThreshold: Player1 %v, Player2 %v", Chess.AIPlayer1Engine.Settings.SolverThreshold, Chess.AIPlayer2Engine.Settings.SolverThreshold)
+		},
+	}
+
+	ResetSolverThresholdMenuItem = &fyne.MenuItem{
+		Action: func() {
+			globalLock.Lock()
+			defer globalLock.Unlock()
+			defer game.Refresh()
+			Chess.UpdateAISettings(Chess.AISettingsPlayers(), func(s *engine.Settings) { s.SolverThreshold = engine.DefaultSolverThreshold })
+			Message.Send("Now SolverThreshold: Player1 %v, Player2 %v", Chess.AIPlayer1Engine.Settings.SolverThreshold, Chess.AIPlayer2Engine.Settings.SolverThreshold)
+		},
+	}
+
 	IncreaseSearchGoroutinesMenuItem = &fyne.MenuItem{
 		Action: func() {
 			globalLock.Lock()
 			defer globalLock.Unlock()
 			defer game.Refresh()
-			Chess.AISearchGoroutines <<= 1
-			Message.Send("Now AISearchGoroutines: %v", Chess.AISearchGoroutines)
+			Chess.UpdateAISettings(Chess.AISettingsPlayers(), func(s *engine.Settings) { s.Goroutines <<= 1 })
+			Message.Send("Now AISearchGoroutines: Player1 %v, Player2 %v", Chess.AIPlayer1Engine.Settings.Goroutines, Chess.AIPlayer2Engine.Settings.Goroutines)
 		},
 		Shortcut: &desktop.CustomShortcut{KeyName: fyne.Key6},
 	}
@@ -264,8 +522,8 @@ func init() {
 			globalLock.Lock()
 			defer globalLock.Unlock()
 			defer game.Refresh()
-			Chess.AISearchGoroutines >>= 1
-			Message.Send("Now AISearchGoroutines: %v", Chess.AISearchGoroutines)
+			Chess.UpdateAISettings(Chess.AISettingsPlayers(), func(s *engine.Settings) { s.Goroutines >>= 1 })
+			Message.Send("Now AISearchGoroutines: Player1 %v, Player2 %v", Chess.AIPlayer1Engine.Settings.Goroutines, Chess.AIPlayer2Engine.Settings.Goroutines)
 		},
 		Shortcut: &desktop.CustomShortcut{KeyName: fyne.Key7},
 	}
@@ -275,8 +533,8 @@ func init() {
 			globalLock.Lock()
 			defer globalLock.Unlock()
 			defer game.Refresh()
-			Chess.AISearchGoroutines = runtime.NumCPU()
-			Message.Send("Now AISearchGoroutines: %v", Chess.AISearchGoroutines)
+			Chess.UpdateAISettings(Chess.AISettingsPlayers(), func(s *engine.Settings) { s.Goroutines = runtime.NumCPU() })
+			Message.Send("Now AISearchGoroutines: Player1 %v, Player2 %v", Chess.AIPlayer1Engine.Settings.Goroutines, Chess.AIPlayer2Engine.Settings.Goroutines)
 		},
 		Shortcut: &desktop.CustomShortcut{KeyName: fyne.Key8},
 	}
@@ -298,7 +556,7 @@ func init() {
 			defer globalLock.Unlock()
 			defer game.Refresh()
 			if !Chess.AutoRestartGame {
-				if CurrentBoard.Size() == AllEdgesCount {
+				if game.Game().Over() {
 					game.Restart(Chess.BoardSize)
 				}
 			}
@@ -309,6 +567,84 @@ func init() {
 		Shortcut: &desktop.CustomShortcut{KeyName: fyne.KeyA},
 	}
 
+	HostNetworkGameMenuItem = &fyne.MenuItem{
+		Action: func() {
+			globalLock.Lock()
+			defer globalLock.Unlock()
+			defer game.Refresh()
+			if Network != nil {
+				LeaveNetworkGame()
+				return
+			}
+			HostNetworkGame()
+		},
+		Shortcut: &desktop.CustomShortcut{KeyName: fyne.KeyN},
+	}
+
+	JoinNetworkGameMenuItem = &fyne.MenuItem{
+		Action: func() {
+			globalLock.Lock()
+			address := Chess.NetworkAddress
+			globalLock.Unlock()
+			if address == "" {
+				address = DefaultNetworkAddress
+			}
+			entry := widget.NewEntry()
+			entry.SetText(address)
+			dialog.ShowForm("Join Network Game", "Join", "Cancel", []*widget.FormItem{widget.NewFormItem("Host Address", entry)}, func(ok bool) {
+				if ok {
+					go JoinNetworkGame(strings.TrimSpace(entry.Text))
+				}
+			}, MainWindow)
+		},
+		Shortcut: &desktop.CustomShortcut{KeyName: fyne.KeyJ},
+	}
+
+	HTTPAPIMenuItem = &fyne.MenuItem{
+		Action: func() {
+			globalLock.Lock()
+			defer globalLock.Unlock()
+			defer game.Refresh()
+			Message.Send(GetMessage("HTTP API", !Chess.HTTPAPI))
+			Chess.HTTPAPI = !Chess.HTTPAPI
+			if Chess.HTTPAPI {
+				StartAPI(Chess.APIAddress)
+			} else {
+				StopAPI()
+			}
+		},
+		Shortcut: &desktop.CustomShortcut{KeyName: fyne.KeyW},
+	}
+
+	APIAddressMenuItem = &fyne.MenuItem{
+		Action: func() {
+			globalLock.Lock()
+			address := Chess.APIAddress
+			globalLock.Unlock()
+			entry := widget.NewEntry()
+			entry.SetText(address)
+			item := widget.NewFormItem("Address", entry)
+			item.HintText = "localhost:6061 for this machine, :6061 for the network"
+			dialog.ShowForm("HTTP API Address", "Set", "Cancel", []*widget.FormItem{item}, func(ok bool) {
+				if !ok {
+					return
+				}
+				globalLock.Lock()
+				defer globalLock.Unlock()
+				defer game.Refresh()
+				Chess.APIAddress = strings.TrimSpace(entry.Text)
+				if Chess.APIAddress == "" {
+					Chess.APIAddress = DefaultAPIAddress
+				}
+				Message.Send("HTTP API Address: %v", Chess.APIAddress)
+				if API != nil {
+					StopAPI()
+					StartAPI(Chess.APIAddress)
+				}
+			}, MainWindow)
+		},
+	}
+
 	QuitMenuItem = &fyne.MenuItem{
 		Action: func() {
 			globalLock.Lock()
@@ -412,12 +748,28 @@ func init() {
 				"Game",
 				RestartGameMenuItem,
 				UndoMenuItem,
+				RedoMenuItem,
+				MoveTreeMenuItem,
 				ScoreMenuItem,
+				AnalysisMenuItem,
+				HintMenuItem,
+				HeatmapMenuItem,
+				ReviewMenuItem,
+				ChartMenuItem,
+				CopyPositionMenuItem,
+				OpenGameMenuItem,
 				SaveScreenshotMenuItem,
 				QuitMenuItem,
 				fyne.NewMenuItemSeparator(),
 				HelpMenuItem,
 			),
+			fyne.NewMenu(
+				"Network",
+				HostNetworkGameMenuItem,
+				JoinNetworkGameMenuItem,
+				HTTPAPIMenuItem,
+				APIAddressMenuItem,
+			),
 			fyne.NewMenu(
 				"Board",
 				IncreaseBoardWidthMenuItem,
@@ -432,7 +784,11 @@ func init() {
 				"Config",
 				AIPlayer1MenuItem,
 				AIPlayer2MenuItem,
+				PonderMenuItem,
+				AIPlayer1StrategyMenuItem,
+				AIPlayer2StrategyMenuItem,
 				fyne.NewMenuItemSeparator(),
+				AISettingsPlayerMenuItem,
 				IncreaseAISearchTimeMenuItem,
 				ReduceAISearchTimeMenuItem,
 				ResetAISearchTimeMenuItem,
@@ -440,6 +796,13 @@ func init() {
 				ReduceSearchGoroutinesMenuItem,
 				ResetSearchGoroutinesMenuItem,
 				fyne.NewMenuItemSeparator(),
+				IncreaseAIExplorationMenuItem,
+				ReduceAIExplorationMenuItem,
+				ResetAIExplorationMenuItem,
+				IncreaseSolverThresholdMenuItem,
+				ReduceSolverThresholdMenuItem,
+				ResetSolverThresholdMenuItem,
+				fyne.NewMenuItemSeparator(),
 				AutoRestartMenuItem,
 				MusicMenuItem,
 			),
@@ -454,8 +817,93 @@ func init() {
 	)
 }
 
+// newStrategyMenu creates the menu choosing the strategy of the given player among the registered ones
+// and the external engine.
+func newStrategyMenu(t engine.Turn) *fyne.Menu {
+	var items []*fyne.MenuItem
+	for _, name := range append(engine.StrategyNames(), engine.ExternalStrategy) {
+		items = append(items, &fyne.MenuItem{
+			Label: name,
+			Action: func() {
+				globalLock.Lock()
+				defer globalLock.Unlock()
+				defer game.Refresh()
+				Chess.AIEngine(t).Strategy = name
+				Message.Send("Now %v Engine: %v", t, name)
+				Scheduler.Notify(EngineEvent)
+			},
+		})
+	}
+	return fyne.NewMenu("", items...)
+}
+
+// refreshStrategyMenu checks the strategy of the given player in its menu.
+func refreshStrategyMenu(item *fyne.MenuItem, t engine.Turn) {
+	item.Disabled = false
+	item.Label = fmt.Sprintf("AI%v Engine: %v", t, Chess.AIEngine(t).Strategy)
+	for _, child := range item.ChildMenu.Items {
+		child.Checked = child.Label == Chess.AIEngine(t).Strategy
+	}
+}
+
+// settingsPlayerName names the players whose search settings the menu changes.
+func settingsPlayerName(t engine.Turn) string {
+	if t == 0 {
+		return "Both Players"
+	}
+	return t.String()
+}
+
+// newSettingsPlayerMenu creates the menu choosing whose search settings the search time, goroutines,
+// exploration and solver threshold items change.
+func newSettingsPlayerMenu() *fyne.Menu {
+	var items []*fyne.MenuItem
+	for _, t := range []engine.Turn{0, engine.Player1Turn, engine.Player2Turn} {
+		items = append(items, &fyne.MenuItem{
+			Label: settingsPlayerName(t),
+			Action: func() {
+				globalLock.Lock()
+				defer globalLock.Unlock()
+				defer game.Refresh()
+				Chess.AISettingsPlayer = t
+				Message.Send("Now AI Settings: %v", settingsPlayerName(t))
+			},
+		})
+	}
+	return fyne.NewMenu("", items...)
+}
+
+// refreshSettingsPlayerMenu checks the players whose search settings the menu changes.
+func refreshSettingsPlayerMenu(item *fyne.MenuItem) {
+	item.Disabled = false
+	item.Label = fmt.Sprintf("AI Settings: %v", settingsPlayerName(Chess.AISettingsPlayer))
+	for _, child := range item.ChildMenu.Items {
+		child.Checked = child.Label == settingsPlayerName(Chess.AISettingsPlayer)
+	}
+}
+
+// anyAISettings reports whether f holds for the search settings of any player the menu changes.
+func anyAISettings(f func(s engine.Settings) bool) bool {
+	for _, t := range Chess.AISettingsPlayers() {
+		if f(Chess.AIEngine(t).Settings) {
+			return true
+		}
+	}
+	return false
+}
+
+// anyAIStrategy reports whether any player the menu changes uses the given strategy.
+func anyAIStrategy(name string) bool {
+	for _, t := range Chess.AISettingsPlayers() {
+		if Chess.AIEngine(t).Strategy == name {
+			return true
+		}
+	}
+	return false
+}
+
 func RefreshMenu() {
-	RestartGameMenuItem.Disabled = CurrentBoard.Size() == 0
+	RestartGameMenuItem.Disabled = game.Game().Step() == 0 || Network.Joined()
 	RestartGameMenuItem.Label = "Restart"
 
 	MusicMenuItem.Disabled = false
@@ -467,6 +915,9 @@ func RefreshMenu() {
 	AIPlayer2MenuItem.Disabled = false
 	AIPlayer2MenuItem.Label = GetMessage("AIPlayer2", !Chess.AIPlayer2)
 
+	PonderMenuItem.Disabled = false
+	PonderMenuItem.Label = GetMessage("AI Pondering", !Chess.AIPonder)
+
 	AutoRestartMenuItem.Disabled = false
 	AutoRestartMenuItem.Label = GetMessage("AutoRestart", !Chess.AutoRestartGame)
 
@@ -479,40 +930,93 @@ func RefreshMenu() {
 	ResetBoardWidthMenuItem.Disabled = Chess.DotCanvasDistance == DefaultDotDistance
 	ResetBoardWidthMenuItem.Label = "Reset BoardWidth"
 
-	IncreaseBoardSizeMenuItem.Disabled = false
+	IncreaseBoardSizeMenuItem.Disabled = Chess.BoardSize >= engine.MaxBoardSize || Network.Joined()
 	IncreaseBoardSizeMenuItem.Label = "Add BoardSize"
 
-	ReduceBoardSizeMenuItem.Disabled = Chess.BoardSize <= MinBoardSize
+	ReduceBoardSizeMenuItem.Disabled = Chess.BoardSize <= MinBoardSize || Network.Joined()
 	ReduceBoardSizeMenuItem.Label = "Reduce BoardSize"
 
-	ResetBoardSizeMenuItem.Disabled = Chess.BoardSize == DefaultBoardSize
+	ResetBoardSizeMenuItem.Disabled = Chess.BoardSize == DefaultBoardSize || Network.Joined()
 	ResetBoardSizeMenuItem.Label = "Reset BoardSize"
 
 	QuitMenuItem.Disabled = false
 	QuitMenuItem.Label = "Quit"
 
-	UndoMenuItem.Disabled = CurrentBoard.Size() == 0
+	UndoMenuItem.Disabled = game.Game().Step() == 0 || Network.Joined()
 	UndoMenuItem.Label = "Undo"
 
+	canRedo := false
+	if game.Tree() != nil {
+		_, canRedo = game.Tree().Next()
+	}
+	RedoMenuItem.Disabled = !canRedo || Network.Joined()
+	RedoMenuItem.Label = "Redo"
+
+	MoveTreeMenuItem.Disabled = false
+	MoveTreeMenuItem.Label = GetMessage("Move Tree", !Chess.MoveTreePanel)
+
 	ScoreMenuItem.Disabled = false
 	ScoreMenuItem.Label = "Score"
 
+	AnalysisMenuItem.Disabled = game.Game().Over()
+	AnalysisMenuItem.Label = "Board Analysis"
+
+	HintMenuItem.Disabled = game.Game().Over() || IsAIPlayer(game.Game().Turn())
+	HintMenuItem.Label = "Hint"
+
+	HeatmapMenuItem.Disabled = false
+	HeatmapMenuItem.Label = GetMessage("Heatmap", !Chess.Heatmap)
+
+	ReviewMenuItem.Disabled = !game.Game().Over() || reviewing.Load()
+	ReviewMenuItem.Label = "Review Game"
+
+	ChartMenuItem.Disabled = game.Game().Step() == 0
+	ChartMenuItem.Label = "Game Chart"
+
+	CopyPositionMenuItem.Disabled = false
+	CopyPositionMenuItem.Label = "Copy Position"
+
+	OpenGameMenuItem.Disabled = false
+	OpenGameMenuItem.Label = "Open Game"
+
 	IncreaseAISearchTimeMenuItem.Disabled = false
 	IncreaseAISearchTimeMenuItem.Label = "Increase AI Search Time"
 
-	ReduceAISearchTimeMenuItem.Disabled = Chess.AISearchTime < time.Millisecond
+	ReduceAISearchTimeMenuItem.Disabled = !anyAISettings(func(s engine.Settings) bool { return s.SearchTime > MinSearchTime })
 	ReduceAISearchTimeMenuItem.Label = "Reduce AI Search Time"
 
-	ResetAISearchTimeMenuItem.Disabled = Chess.AISearchTime == DefaultStepTime
+	ResetAISearchTimeMenuItem.Disabled = !anyAISettings(func(s engine.Settings) bool { return s.SearchTime != DefaultStepTime })
 	ResetAISearchTimeMenuItem.Label = "Reset AI Search Time"
 
+	refreshStrategyMenu(AIPlayer1StrategyMenuItem, engine.Player1Turn)
+	refreshStrategyMenu(AIPlayer2StrategyMenuItem, engine.Player2Turn)
+	refreshSettingsPlayerMenu(AISettingsPlayerMenuItem)
+
+	IncreaseAIExplorationMenuItem.Disabled = !anyAIStrategy(engine.MCTSStrategy)
+	IncreaseAIExplorationMenuItem.Label = "Increase MCTS Exploration"
+
+	ReduceAIExplorationMenuItem.Disabled = !anyAIStrategy(engine.MCTSStrategy) || !anyAISettings(func(s engine.Settings) bool { return s.Exploration > MinExploration })
+	ReduceAIExplorationMenuItem.Label = "Reduce MCTS Exploration"
+
+	ResetAIExplorationMenuItem.Disabled = !anyAIStrategy(engine.MCTSStrategy) || !anyAISettings(func(s engine.Settings) bool { return s.Exploration != engine.DefaultExploration })
+	ResetAIExplorationMenuItem.Label = "Reset MCTS Exploration"
+
+	IncreaseSolverThresholdMenuItem.Disabled = false
+	IncreaseSolverThresholdMenuItem.Label = "Increase Endgame Solver Threshold"
+
+	ReduceSolverThresholdMenuItem.Disabled = !anyAISettings(func(s engine.Settings) bool { return s.SolverThreshold > 0 })
+	ReduceSolverThresholdMenuItem.Label = "Reduce Endgame Solver Threshold"
+
+	ResetSolverThresholdMenuItem.Disabled = !anyAISettings(func(s engine.Settings) bool { return s.SolverThreshold != engine.DefaultSolverThreshold })
+	ResetSolverThresholdMenuItem.Label = "Reset Endgame Solver Threshold"
+
 	IncreaseSearchGoroutinesMenuItem.Disabled = false
 	IncreaseSearchGoroutinesMenuItem.Label = "Increase Search Goroutines"
 
-	ReduceSearchGoroutinesMenuItem.Disabled = Chess.AISearchGoroutines <= 1
+	ReduceSearchGoroutinesMenuItem.Disabled = !anyAISettings(func(s engine.Settings) bool { return s.Goroutines > MinSearchGoroutines })
 	ReduceSearchGoroutinesMenuItem.Label = "Reduce Search Goroutines"
 
-	ResetSearchGoroutinesMenuItem.Disabled = Chess.AISearchGoroutines == runtime.NumCPU()
+	ResetSearchGoroutinesMenuItem.Disabled = !anyAISettings(func(s engine.Settings) bool { return s.Goroutines != runtime.NumCPU() })
 	ResetSearchGoroutinesMenuItem.Label = "Reset Search Goroutines"
 
 	IncreasePerformanceAnalysisTimeMenuItem.Disabled = false
@@ -533,5 +1037,20 @@ func RefreshMenu() {
 	SaveScreenshotMenuItem.Disabled = false
 	SaveScreenshotMenuItem.Label = "Save Screenshot"
 
+	HostNetworkGameMenuItem.Disabled = false
+	HostNetworkGameMenuItem.Label = "Host Network Game"
+	if Network != nil {
+		HostNetworkGameMenuItem.Label = "Leave Network Game"
+	}
+
+	JoinNetworkGameMenuItem.Disabled = Network != nil
+	JoinNetworkGameMenuItem.Label = "Join Network Game"
+
+	HTTPAPIMenuItem.Disabled = false
+	HTTPAPIMenuItem.Label = GetMessage("HTTP API", !Chess.HTTPAPI)
+
+	APIAddressMenuItem.Disabled = false
+	APIAddressMenuItem.Label = "Set HTTP API Address"
+
 	MainWindow.MainMenu().Refresh()
 }
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/widget"
+	"github.com/HuXin0817/dots-and-boxes/engine"
+)
+
+// MoveTreePanelWidth is the width of the move tree panel next to the board.
+const MoveTreePanelWidth = 240
+
+// moveTreePanel browses the move tree of the game. The main line of every node is listed flat, and a move with
+// alternatives has a branch per variation. Tapping a move brings the board back to it.
+// The panel shows a snapshot of the tree taken under globalLock, so that the widget never reads the live tree.
+type moveTreePanel struct {
+	lock     sync.Mutex          // Mutex for snapshot synchronization
+	children map[string][]string // Children of each item, the root being ""
+	labels   map[string]string   // Text of each item
+	current  map[string]bool     // Items on the line of the current node
+	paths    map[string][]int    // Path in the move tree of each move item
+	widget   *widget.Tree        // Tree widget of the panel
+}
+
+// newMoveTreePanel creates the panel of a move tree.
+func newMoveTreePanel(t *engine.MoveTree) *moveTreePanel {
+	p := &moveTreePanel{}
+	p.update(t)
+	p.widget = widget.NewTree(
+		func(id widget.TreeNodeID) []widget.TreeNodeID {
+			p.lock.Lock()
+			defer p.lock.Unlock()
+			return p.children[id]
+		},
+		func(id widget.TreeNodeID) bool {
+			p.lock.Lock()
+			defer p.lock.Unlock()
+			return len(p.children[id]) > 0
+		},
+		func(bool) fyne.CanvasObject { return widget.NewLabel("") },
+		func(id widget.TreeNodeID, _ bool, o fyne.CanvasObject) {
+			p.lock.Lock()
+			defer p.lock.Unlock()
+			label := o.(*widget.Label)
+			label.TextStyle.Bold = p.current[id]
+			label.SetText(p.labels[id])
+		},
+	)
+	p.widget.OnSelected = func(id widget.TreeNodeID) {
+		p.widget.Unselect(id)
+		p.lock.Lock()
+		path, ok := p.paths[id]
+		p.lock.Unlock()
+		if !ok {
+			return
+		}
+		globalLock.Lock()
+		defer globalLock.Unlock()
+		if Network.Joined() {
+			Message.Send(NetworkHostOnlyMessage)
+			return
+		}
+		defer game.Refresh()
+		game.GotoMove(path)
+	}
+	return p
+}
+
+// pathID returns the item of a path, prefixed by m for a move or v for the variation starting with that move.
+func pathID(prefix string, path []int) string {
+	s := make([]string, len(path))
+	for i, index := range path {
+		s[i] = strconv.Itoa(index)
+	}
+	return prefix + strings.Join(s, ".")
+}
+
+// nextMainLine returns the path of the main line move after the path, and its node.
+func nextMainLine(t *engine.MoveTree, path []int) ([]int, *engine.MoveNode) {
+	path = append(slices.Clone(path), 0)
+	return path, t.Node(path)
+}
+
+// update takes a snapshot of the move tree. It must be called with globalLock held.
+func (p *moveTreePanel) update(t *engine.MoveTree) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.children = make(map[string][]string)
+	p.labels = make(map[string]string)
+	p.current = make(map[string]bool)
+	p.paths = make(map[string][]int)
+
+	// line adds the moves from the node of the path down its main line to the items of parent
+	var line func(parent string, path []int)
+	line = func(parent string, path []int) {
+		if len(path) == 0 {
+			path = []int{0} // The root is not a move
+		}
+		for n := t.Node(path); n != nil; path, n = nextMainLine(t, path) {
+			id := pathID("m", path)
+			p.children[parent] = append(p.children[parent], id)
+			p.paths[id] = path
+			r := n.Record
+			p.labels[id] = fmt.Sprintf("%v. %v %v", r.Step+1, r.Player, r.Move)
+			if len(path) <= len(t.Current) && slices.Equal(path, t.Current[:len(path)]) {
+				p.current[id] = true
+				if len(path) == len(t.Current) {
+					p.labels[id] = "▶ " + p.labels[id]
+				}
+			}
+			// The alternatives to a main line move are its variations
+			parentNode := t.Node(path[:len(path)-1])
+			if path[len(path)-1] != 0 {
+				continue
+			}
+			for i := 1; i < len(parentNode.Children); i++ {
+				start := append(slices.Clone(path[:len(path)-1]), i)
+				v := pathID("v", start)
+				p.children[id] = append(p.children[id], v)
+				p.labels[v] = "Variation " + parentNode.Children[i].Record.Move
+				line(v, start)
+			}
+		}
+	}
+	line("", nil)
+}
+
+// Refresh takes a snapshot of the move tree and redraws the panel. It must be called with globalLock held.
+func (p *moveTreePanel) Refresh(t *engine.MoveTree) {
+	p.update(t)
+	p.widget.Refresh()
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/HuXin0817/dots-and-boxes/engine"
+)
+
+const (
+	NetworkPort            = 7290                                     // Port on which a network game is hosted
+	NetworkDialTimeout     = 5 * time.Second                          // Timeout of the connection to the host
+	DefaultNetworkAddress  = "localhost:7290"                         // Default address of the host to join
+	NetworkHostSeat        = engine.Player1Turn                       // Player of the host, the joining side playing the other one
+	NetworkHostOnlyMessage = "Only the Host Changes the Network Game" // Sent when the joining side tries to change the game
+)
+
+// netSession is the network game of this instance, as the host or as the joining side. The host owns the game and
+// sends its state to the joining side whenever it changes, and plays the moves of the joining side after checking
+// them against its own board. The fields are guarded by globalLock.
+type netSession struct {
+	host     bool            // Whether this instance hosts the game
+	seat     engine.Turn     // Player of this instance
+	listener net.Listener    // Listener of the host until a player joins, nil otherwise
+	conn     *engine.NetConn // Connection to the other side, nil while the host waits for a player
+	sent     string          // Key of the state last sent by the host
+}
+
+// Network is the network game of this instance, nil when playing locally.
+var Network *netSession
+
+// Local checks if the player is played on this instance. Every player is local when playing locally.
+func (n *netSession) Local(t engine.Turn) bool { return n == nil || n.seat == t }
+
+// Joined checks if this instance joined a game hosted by another one.
+func (n *netSession) Joined() bool { return n != nil && !n.host }
+
+// Play draws the edge chosen on this instance. The joining side sends it to the host instead, which plays it back
+// in its next state.
+func (n *netSession) Play(u UI, e engine.Edge) {
+	if !n.Joined() {
+		u.AddEdge(e)
+		return
+	}
+	if u.Game().Validate(e) != nil {
+		return
+	}
+	if err := n.conn.Send(engine.NetMessage{Type: engine.NetMove, Move: u.Game().EdgeString(e)}); err != nil {
+		Message.Send(err.Error())
+	}
+}
+
+// Sync sends the state of the game to the joining side if it changed since it was last sent.
+// It is called by every refresh of the host.
+func (n *netSession) Sync(g *engine.Game) {
+	if n == nil || !n.host || n.conn == nil {
+		return
+	}
+	key := g.Position().String() + "/" + strconv.Itoa(g.Step())
+	if key == n.sent {
+		return
+	}
+	n.sent = key
+	if err := n.conn.Send(engine.NetMessage{Type: engine.NetState, BoardSize: g.BoardSize, Records: g.Records()}); err != nil {
+		Message.Send(err.Error())
+	}
+}
+
+// Close ends the network game.
+func (n *netSession) Close() {
+	if n.listener != nil {
+		if err := n.listener.Close(); err != nil {
+			Message.Send(err.Error())
+		}
+	}
+	if n.conn != nil {
+		if err := n.conn.Close(); err != nil {
+			Message.Send(err.Error())
+		}
+	}
+}
+
+// LeaveNetworkGame ends the network game and goes back to local play. It must be called with globalLock held.
+func LeaveNetworkGame() {
+	if Network == nil {
+		return
+	}
+	Network.Close()
+	Network = nil
+	Message.Send("Left Network Game")
+	Scheduler.Notify(ToggleEvent)
+}
+
+// HostNetworkGame starts hosting a game on NetworkPort, and waits for a player in the background.
+// It must be called with globalLock held.
+func HostNetworkGame() {
+	l, err := net.Listen("tcp", fmt.Sprintf(":%v", NetworkPort))
+	if err != nil {
+		Message.Send(err.Error())
+		return
+	}
+	n := &netSession{host: true, seat: NetworkHostSeat, listener: l}
+	Network = n
+	Scheduler.Notify(ToggleEvent)
+	Message.Send("Hosting Network Game on Port %v, %v Waits for a Player", NetworkPort, n.seat)
+	go n.accept()
+}
+
+// accept waits for a player with the same protocol version, then serves its moves.
+func (n *netSession) accept() {
+	for {
+		c, err := n.listener.Accept()
+		if err != nil {
+			n.end(err)
+			return
+		}
+		conn := engine.NewNetConn(c)
+		_ = c.SetReadDeadline(time.Now().Add(NetworkDialTimeout)) // A silent client must not keep the seat
+		m, err := conn.Receive()
+		_ = c.SetReadDeadline(time.Time{})
+		if err == nil && m.Type != engine.NetHello {
+			err = fmt.Errorf("expected %v, got %v", engine.NetHello, m.Type)
+		}
+		if err != nil {
+			_ = conn.Send(engine.NetMessage{Type: engine.NetError, Error: err.Error()})
+			_ = conn.Close()
+			continue
+		}
+
+		globalLock.Lock()
+		if Network != n {
+			globalLock.Unlock()
+			_ = conn.Close()
+			return
+		}
+		_ = n.listener.Close()
+		n.listener, n.conn = nil, conn
+		if err := conn.Send(engine.NetMessage{Type: engine.NetWelcome, Seat: -n.seat}); err != nil {
+			Message.Send(err.Error())
+		}
+		Message.Send("%v Joined from %v", -n.seat, c.RemoteAddr())
+		Scheduler.Notify(ToggleEvent)
+		game.Refresh()
+		globalLock.Unlock()
+		n.serve()
+		return
+	}
+}
+
+// JoinNetworkGame connects to the game hosted at the address, and plays it until either side leaves.
+// It dials without holding globalLock.
+func JoinNetworkGame(address string) {
+	c, err := net.DialTimeout("tcp", address, NetworkDialTimeout)
+	if err != nil {
+		Message.Send(err.Error())
+		return
+	}
+	conn := engine.NewNetConn(c)
+	var m engine.NetMessage
+	err = conn.Send(engine.NetMessage{Type: engine.NetHello, BoardSize: Chess.BoardSize}) // Board size of a quick match in a lobby
+	if err == nil {
+		m, err = conn.Receive()
+	}
+	if err == nil && m.Type == engine.NetError {
+		err = errors.New(m.Error)
+	} else if err == nil && m.Type != engine.NetWelcome {
+		err = fmt.Errorf("expected %v, got %v", engine.NetWelcome, m.Type)
+	}
+	if err != nil {
+		Message.Send("Cannot Join %v: %v", address, err)
+		_ = conn.Close()
+		return
+	}
+
+	globalLock.Lock()
+	if Network != nil {
+		globalLock.Unlock()
+		_ = conn.Close()
+		return
+	}
+	n := &netSession{seat: m.Seat, conn: conn}
+	Network = n
+	Chess.NetworkAddress = address
+	Scheduler.Notify(ToggleEvent)
+	Message.Send("Joined Network Game at %v as %v", address, n.seat)
+	game.Refresh()
+	globalLock.Unlock()
+	n.serve()
+}
+
+// serve handles the messages of the other side until the connection ends.
+func (n *netSession) serve() {
+	for {
+		m, err := n.conn.Receive()
+		if err != nil {
+			n.end(err)
+			return
+		}
+		globalLock.Lock()
+		if Network != n {
+			globalLock.Unlock()
+			return
+		}
+		switch {
+		case n.host && m.Type == engine.NetMove:
+			n.playRemote(m.Move)
+		case !n.host && m.Type == engine.NetState:
+			n.apply(m)
+		case !n.host && m.Type == engine.NetError:
+			Message.Send("Move Rejected: %v", m.Error)
+			Scheduler.Notify(ToggleEvent) // Let the AI player of this instance search again
+		default:
+			_ = n.conn.Send(engine.NetMessage{Type: engine.NetError, Error: fmt.Sprintf("unexpected %v", m.Type)})
+		}
+		game.Refresh()
+		globalLock.Unlock()
+	}
+}
+
+// playRemote plays the move of the joining side if it is its turn and the edge is free on the board of the host.
+// Otherwise it rejects the move and sends the state again, so that the joining side catches up with the host.
+func (n *netSession) playRemote(move string) {
+	g := game.Game()
+	e, err := g.ParseEdge(move)
+	if err == nil && g.Turn() == n.seat {
+		err = fmt.Errorf("it is the turn of %v", n.seat)
+	}
+	if err == nil {
+		err = g.Validate(e)
+	}
+	if err != nil {
+		_ = n.conn.Send(engine.NetMessage{Type: engine.NetError, Error: fmt.Sprintf("%v: %v", move, err)})
+		n.sent = ""
+		return
+	}
+	game.AddEdge(e)
+}
+
+// apply brings the game of the joining side to the state sent by the host. A state adding a move to the game
+// is played as a move, any other change replays the game.
+func (n *netSession) apply(m engine.NetMessage) {
+	g := game.Game()
+	moves := func(records []engine.MoveRecord) []string {
+		s := make([]string, len(records))
+		for i, r := range records {
+			s[i] = r.Move
+		}
+		return s
+	}
+	if m.BoardSize < MinBoardSize || m.BoardSize > engine.MaxBoardSize {
+		_ = n.conn.Send(engine.NetMessage{Type: engine.NetError, Error: fmt.Sprintf("invalid board size %v", m.BoardSize)})
+		return
+	}
+	local, remote := moves(g.Records()), moves(m.Records)
+	if m.BoardSize == g.BoardSize && slices.Equal(local, remote) {
+		return
+	}
+	if m.BoardSize == g.BoardSize && len(remote) == len(local)+1 && slices.Equal(local, remote[:len(local)]) {
+		if e, err := g.ParseEdge(remote[len(local)]); err == nil {
+			game.AddEdge(e)
+			return
+		}
+	}
+	Chess.BoardSize = m.BoardSize
+	game.Recover(m.Records)
+}
+
+// end goes back to local play when the connection of the network game ends, unless it was left on purpose.
+func (n *netSession) end(err error) {
+	globalLock.Lock()
+	defer globalLock.Unlock()
+	if Network != n {
+		return
+	}
+	n.Close()
+	Network = nil
+	Message.Send("Network Game Ended: %v", err)
+	Scheduler.Notify(ToggleEvent)
+	game.Refresh()
+}
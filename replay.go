@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+	"github.com/HuXin0817/dots-and-boxes/engine"
+	"github.com/bytedance/sonic"
+)
+
+const (
+	GameLogFileSuffix = ".log"  // Suffix of the text game log
+	GameFileSuffix    = ".json" // Suffix of the structured game log saved next to the text game log
+)
+
+// ReplaySpeeds are the delays between two moves of the autoplay of the replay window.
+var ReplaySpeeds = []time.Duration{250 * time.Millisecond, 500 * time.Millisecond, time.Second, 2 * time.Second}
+
+// LoadGame reads a structured game log, or a text game log for any other suffix.
+func LoadGame(fileName string) (engine.GameLog, error) {
+	b, err := os.ReadFile(fileName)
+	if err != nil {
+		return engine.GameLog{}, err
+	}
+	if !strings.HasSuffix(fileName, GameFileSuffix) {
+		return engine.ParseGameLog(bytes.NewReader(b))
+	}
+	var l engine.GameLog
+	if err := sonic.Unmarshal(b, &l); err != nil {
+		return l, err
+	}
+	if _, err := l.Game(); err != nil {
+		return l, err
+	}
+	return l, nil
+}
+
+// saveGame writes the structured game log to a JSON file.
+func saveGame(fileName string, l engine.GameLog) error {
+	j, err := sonic.Marshal(l)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(fileName, j, 0666)
+}
+
+// OpenGame loads a game log and opens it in a replay window.
+func OpenGame(fileName string) {
+	l, err := LoadGame(fileName)
+	if err != nil {
+		Message.Send("Cannot Open %v: %v", filepath.Base(fileName), err)
+		return
+	}
+	Message.Send("Opened %v, BoardSize: %v, Moves: %v", filepath.Base(fileName), l.BoardSize, len(l.Records))
+	showReplay(filepath.Base(fileName), l)
+}
+
+// showReplay opens a window stepping through the moves of the game log, which can play them at a chosen speed
+// and continue the game from the shown step as a new live game.
+func showReplay(name string, l engine.GameLog) {
+	if l.BoardSize < 2 || l.BoardSize > engine.MaxBoardSize {
+		Message.Send("Cannot Open %v: invalid board size %v", name, l.BoardSize)
+		return
+	}
+	t := engine.NewTopology(l.BoardSize)
+	w := fyne.CurrentApp().NewWindow("Replay " + name)
+	board := container.NewWithoutLayout()
+	boardSize := float32(ReviewDotDistance * l.BoardSize)
+	label := widget.NewLabel("")
+
+	var (
+		lock    sync.Mutex    // Mutex for step and autoplay synchronization
+		step    int           // Number of moves shown
+		playing chan struct{} // Closed to stop the autoplay, nil if it is not running
+	)
+	var first, prev, next, last, play *widget.Button
+	show := func(i int) {
+		step = i
+		board.Objects = drawBoard(t, l.Records[:step], engine.InvalidEdge)
+		board.Refresh()
+		score1, score2, move := l.Player1Score, l.Player2Score, "Start"
+		if step < len(l.Records) {
+			score1, score2 = l.Records[step].Player1Score, l.Records[step].Player2Score
+		}
+		if step > 0 {
+			r := l.Records[step-1]
+			move = fmt.Sprintf("%v %v", r.Player, t.EdgeString(r.MoveEdge))
+		}
+		label.SetText(fmt.Sprintf("Step %v/%v, %v\nPlayer1 Score: %v, Player2 Score: %v", step, len(l.Records), move, score1, score2))
+		for _, b := range []*widget.Button{first, prev} {
+			b.Disable()
+			if step > 0 {
+				b.Enable()
+			}
+		}
+		for _, b := range []*widget.Button{next, last, play} {
+			b.Disable()
+			if step < len(l.Records) {
+				b.Enable()
+			}
+		}
+	}
+	stop := func() {
+		if playing != nil {
+			close(playing)
+			playing = nil
+			play.SetText("Play")
+		}
+	}
+	goTo := func(i int) {
+		lock.Lock()
+		defer lock.Unlock()
+		stop()
+		show(i)
+	}
+	first = widget.NewButton("First", func() { goTo(0) })
+	prev = widget.NewButton("Previous", func() { goTo(step - 1) })
+	next = widget.NewButton("Next", func() { goTo(step + 1) })
+	last = widget.NewButton("Last", func() { goTo(len(l.Records)) })
+
+	var speeds []string
+	for _, d := range ReplaySpeeds {
+		speeds = append(speeds, fmt.Sprintf("%v per Move", d))
+	}
+	speed := widget.NewSelect(speeds, nil)
+	speed.SetSelectedIndex(len(ReplaySpeeds) / 2)
+	play = widget.NewButton("Play", func() {
+		lock.Lock()
+		defer lock.Unlock()
+		if playing != nil {
+			stop()
+			return
+		}
+		stopped := make(chan struct{})
+		playing = stopped
+		play.SetText("Pause")
+		go func() {
+			for {
+				select {
+				case <-stopped:
+					return
+				case <-time.After(ReplaySpeeds[max(speed.SelectedIndex(), 0)]):
+				}
+				lock.Lock()
+				if playing != stopped {
+					lock.Unlock()
+					return
+				}
+				show(step + 1)
+				if step == len(l.Records) {
+					stop()
+				}
+				lock.Unlock()
+			}
+		}()
+	})
+
+	branch := widget.NewButton("Continue Playing from Here", func() {
+		lock.Lock()
+		stop()
+		records := append([]engine.MoveRecord{}, l.Records[:step]...)
+		lock.Unlock()
+		now := time.Now()
+		for i := range records {
+			records[i].TimeStamp = now // The branch is a new game with a log of its own
+		}
+		globalLock.Lock()
+		defer globalLock.Unlock()
+		if Network.Joined() {
+			Message.Send(NetworkHostOnlyMessage)
+			return
+		}
+		defer game.Refresh()
+		game.Restart(l.BoardSize) // A new move tree
+		game.Recover(records)
+		Message.Send("Continue %v from Step %v", name, len(records))
+	})
+	w.SetOnClosed(func() {
+		lock.Lock()
+		defer lock.Unlock()
+		stop()
+	})
+
+	controls := container.NewVBox(label, container.NewHBox(first, prev, next, last), container.NewHBox(play, speed), branch)
+	w.SetContent(container.NewBorder(nil, controls, nil, nil, container.NewGridWrap(fyne.NewSize(boardSize, boardSize), board)))
+	show(0)
+	w.Show()
+}
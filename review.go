@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"image/color"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+	"github.com/HuXin0817/dots-and-boxes/engine"
+	"github.com/bytedance/sonic"
+)
+
+const (
+	ReviewFileSuffix  = ".review.json" // Suffix of the review file saved next to the game log
+	ReviewDotDistance = 48             // Distance between dots on the board of the review window
+)
+
+var (
+	ReviewBestEdgeColor = color.NRGBA{R: 0x40, G: 0xC0, B: 0x40, A: 0xFF} // #40C040FF, edge recommended by the engine
+	reviewing           atomic.Bool                                       // Whether a game is being reviewed
+)
+
+// GameName returns the name of the game of the move records, which names its log file.
+func GameName(records []engine.MoveRecord) string {
+	return fmt.Sprintf("Game %v", records[0].TimeStamp.Format(time.DateTime))
+}
+
+// ReviewGame searches every position of the move records in the background with a new instance of the engine
+// of AI Player 1, showing its progress in a small window, then saves the review next to the game log and opens it in a
+// review window.
+func ReviewGame(boardSize int, records []engine.MoveRecord) {
+	if len(records) == 0 || !reviewing.CompareAndSwap(false, true) {
+		return
+	}
+	name, settings := Chess.AIPlayer1Engine.Strategy, Chess.AIPlayer1Engine.Settings
+	strategy, err := engine.NewStrategy(name)
+	if err != nil {
+		name = engine.DefaultStrategy
+		strategy, _ = engine.NewStrategy(name)
+	}
+	progress := widget.NewProgressBar()
+	w := fyne.CurrentApp().NewWindow(fmt.Sprintf("Reviewing %v Moves with %v", len(records), name))
+	w.SetContent(container.NewPadded(progress))
+	w.Resize(fyne.NewSize(360, 0))
+	w.Show()
+	go func() {
+		defer reviewing.Store(false)
+		review, err := engine.ReviewGame(context.Background(), boardSize, records, strategy, settings, func(done, total int) {
+			progress.SetValue(float64(done) / float64(total))
+		})
+		w.Close()
+		if err != nil {
+			Message.Send(err.Error())
+			return
+		}
+		review.Strategy = name
+		Message.Send("Review Done, Player1 Inaccuracies: %v, Blunders: %v, Player2 Inaccuracies: %v, Blunders: %v",
+			review.Inaccuracies[0], review.Blunders[0], review.Inaccuracies[1], review.Blunders[1])
+		if err := saveReview(GameName(records)+ReviewFileSuffix, review); err != nil {
+			Message.Send(err.Error())
+		}
+		showReview(review)
+	}()
+}
+
+// saveReview writes the review to a JSON file.
+func saveReview(fileName string, review engine.GameReview) error {
+	j, err := sonic.Marshal(review)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(fileName, j, 0666)
+}
+
+// showReview opens a window stepping through the moves of the review.
+func showReview(review engine.GameReview) {
+	if len(review.Moves) == 0 {
+		return
+	}
+	t := engine.NewTopology(review.BoardSize)
+	w := fyne.CurrentApp().NewWindow(fmt.Sprintf("Review by %v", review.Strategy))
+	board := container.NewWithoutLayout()
+	boardSize := float32(ReviewDotDistance * review.BoardSize)
+	board.Resize(fyne.NewSize(boardSize, boardSize))
+	label := widget.NewLabel("")
+	step := 0
+
+	var prev, next, nextMistake *widget.Button
+	show := func(i int) {
+		step = i
+		m := review.Moves[step]
+		board.Objects = drawBoard(t, reviewRecords(review.Moves[:step+1]), m.BestEdge)
+		board.Refresh()
+		label.SetText(fmt.Sprintf("Step %v/%v, %v: %v\nPlayed %v, Margin %+.1f\nBest %v, Margin %+.1f\nLost %.1f, %v",
+			step+1, len(review.Moves), m.Player, m.Label,
+			t.EdgeString(m.MoveEdge), m.MoveMargin, t.EdgeString(m.BestEdge), m.BestMargin, m.MarginLost, exactString(m.Exact)))
+		prev.Disable()
+		if step > 0 {
+			prev.Enable()
+		}
+		next.Disable()
+		if step+1 < len(review.Moves) {
+			next.Enable()
+		}
+	}
+	prev = widget.NewButton("Previous", func() { show(step - 1) })
+	next = widget.NewButton("Next", func() { show(step + 1) })
+	nextMistake = widget.NewButton("Next Mistake", func() {
+		for i := step + 1; i < len(review.Moves); i++ {
+			if review.Moves[i].Label != engine.OkMove {
+				show(i)
+				return
+			}
+		}
+	})
+
+	w.SetContent(container.NewBorder(nil, container.NewVBox(label, container.NewHBox(prev, next, nextMistake)), nil, nil,
+		container.NewGridWrap(fyne.NewSize(boardSize, boardSize), board)))
+	show(0)
+	w.Show()
+}
+
+// exactString describes whether margins are proven.
+func exactString(exact bool) string {
+	if exact {
+		return "exact"
+	}
+	return "estimated"
+}
+
+// reviewRecords returns the move records of the reviewed moves.
+func reviewRecords(moves []engine.MoveReview) []engine.MoveRecord {
+	records := make([]engine.MoveRecord, len(moves))
+	for i, m := range moves {
+		records[i] = engine.MoveRecord{Step: m.Step, Player: m.Player, MoveEdge: m.MoveEdge}
+	}
+	return records
+}
+
+// drawBoard draws the position after the move records: the edges and boxes in the colors of their players,
+// the last move highlighted and, unless it is engine.InvalidEdge, the edge recommended instead of it.
+func drawBoard(t *engine.Topology, records []engine.MoveRecord, bestEdge engine.Edge) []fyne.CanvasObject {
+	pos := func(d engine.Dot) fyne.Position {
+		return fyne.NewPos(float32(t.X(d))*ReviewDotDistance+ReviewDotDistance/2, float32(t.Y(d))*ReviewDotDistance+ReviewDotDistance/2)
+	}
+	line := func(e engine.Edge, c color.Color, width float32) *canvas.Line {
+		l := canvas.NewLine(c)
+		l.Position1, l.Position2 = pos(t.Dot1(e)), pos(t.Dot2(e))
+		l.StrokeWidth = width
+		return l
+	}
+
+	g := engine.NewGame(t.BoardSize)
+	var objects []fyne.CanvasObject
+	for _, r := range records {
+		if _, err := g.Play(r.MoveEdge); err != nil {
+			break
+		}
+	}
+	for _, box := range t.Boxes {
+		if owner := g.BoxOwner(box); owner != 0 {
+			r := canvas.NewRectangle(gameTheme.GetPlayerFilledColor(owner))
+			r.Move(pos(engine.Dot(box)))
+			r.Resize(fyne.NewSize(ReviewDotDistance, ReviewDotDistance))
+			objects = append(objects, r)
+		}
+	}
+	for i, r := range records {
+		width := float32(ReviewDotDistance / 10)
+		if i == len(records)-1 {
+			width *= 2
+		}
+		objects = append(objects, line(r.MoveEdge, gameTheme.GetPlayerHighlightColor(r.Player), width))
+	}
+	if bestEdge != engine.InvalidEdge && (len(records) == 0 || bestEdge != records[len(records)-1].MoveEdge) {
+		objects = append(objects, line(bestEdge, ReviewBestEdgeColor, ReviewDotDistance/10))
+	}
+	for _, d := range t.Dots {
+		c := canvas.NewCircle(gameTheme.GetDotCanvasColor())
+		c.Resize(fyne.NewSize(ReviewDotDistance/5, ReviewDotDistance/5))
+		c.Move(pos(d).Subtract(fyne.NewPos(ReviewDotDistance/10, ReviewDotDistance/10)))
+		objects = append(objects, c)
+	}
+	return objects
+}
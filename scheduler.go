@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+
+	"github.com/HuXin0817/dots-and-boxes/engine"
+)
+
+// AIEvent is a change of the game that the AI scheduler reacts to.
+type AIEvent int
+
+// Events of the game
+const (
+	MoveEvent    AIEvent = iota // An edge was drawn
+	RestartEvent                // The game was restarted or recovered from move records
+	ToggleEvent                 // An AI player was started or stopped
+	EngineEvent                 // The engine of an AI player was changed
+	HintEvent                   // A hint was requested by the human to move
+	UndoEvent                   // The last edge was undone
+)
+
+// String returns the name of the event.
+func (e AIEvent) String() string {
+	switch e {
+	case MoveEvent:
+		return "Move"
+	case RestartEvent:
+		return "Restart"
+	case ToggleEvent:
+		return "Toggle"
+	case EngineEvent:
+		return "Engine"
+	case HintEvent:
+		return "Hint"
+	case UndoEvent:
+		return "Undo"
+	default:
+		return "Unknown"
+	}
+}
+
+// AIScheduler decides when the AI players move. A single goroutine runs the searches, so that at most one search is
+// in flight, and every event of the game cancels the running search since the position it started from is outdated.
+// With pondering on, the AI player waiting for a human keeps searching until the human moves.
+// The fields are guarded by globalLock.
+type AIScheduler struct {
+	events     chan AIEvent       // Pending event, a single one is enough since the state is read when it is handled
+	generation int                // Incremented on every event
+	cancel     context.CancelFunc // Cancels the running search, nil if none is running
+	hint       bool               // Whether a hint was requested for the current position
+	evaluated  int                // Generation whose heatmap is shown
+}
+
+// aiTask is a search run by the scheduler.
+type aiTask int
+
+// Searches run by the scheduler, by decreasing priority
+const (
+	idleTask    aiTask = iota // Nothing to search
+	moveTask                  // Search and play the move of the AI player to move
+	hintTask                  // Rank the edges for the human to move
+	heatmapTask               // Evaluate every free edge for the human to move
+	ponderTask                // Search for the AI player waiting for the human to move
+)
+
+// Scheduler plays the moves of the AI players of the game.
+var Scheduler = NewAIScheduler()
+
+// NewAIScheduler creates a new AI scheduler.
+func NewAIScheduler() *AIScheduler {
+	return &AIScheduler{events: make(chan AIEvent, 1)}
+}
+
+// Notify tells the scheduler about an event of the game. It must be called with globalLock held.
+func (s *AIScheduler) Notify(e AIEvent) {
+	s.generation++
+	if s.cancel != nil {
+		s.cancel()
+		s.cancel = nil
+	}
+	s.hint = false
+	s.signal(e)
+}
+
+// RequestHint asks the scheduler to search the position of the human to move with the engine configured for that
+// player, and to show the edges it recommends. It must be called with globalLock held.
+func (s *AIScheduler) RequestHint() {
+	evaluated := s.evaluated == s.generation
+	s.Notify(HintEvent)
+	s.hint = true
+	if evaluated {
+		s.evaluated = s.generation // The position did not change, so the heatmap shown is still valid
+	}
+}
+
+// signal wakes the scheduler up without changing the generation.
+func (s *AIScheduler) signal(e AIEvent) {
+	select {
+	case s.events <- e:
+	default:
+	}
+}
+
+// Run handles the events of the game shown by u, searching and playing a move whenever an AI player is to move.
+// While a human is to move, it searches the requested hint, then the heatmap, then ponders against an AI player.
+func (s *AIScheduler) Run(u UI) {
+	for range s.events {
+		s.handle(u)
+	}
+}
+
+// task returns the search needed by the current state of game g. It must be called with globalLock held.
+func (s *AIScheduler) task(g *engine.Game) aiTask {
+	turn := g.Turn()
+	switch {
+	case g.Over():
+		return idleTask
+	case IsAIPlayer(turn):
+		return moveTask
+	case s.hint:
+		return hintTask
+	case Chess.Heatmap && s.evaluated != s.generation:
+		return heatmapTask
+	case Chess.AIPonder && IsAIPlayer(-turn):
+		return ponderTask
+	default:
+		return idleTask
+	}
+}
+
+// handle runs the search needed by the current state of the game without holding globalLock, so that the game stays
+// responsive during long searches. The result is used only if no event happened since the search started.
+func (s *AIScheduler) handle(u UI) {
+	globalLock.Lock()
+	g := u.Game()
+	task := s.task(g)
+	if task == idleTask {
+		globalLock.Unlock()
+		return
+	}
+	turn := g.Turn()
+	if task == ponderTask {
+		turn = -turn
+	}
+	strategy, settings := GetAIStrategy(turn)
+	if strategy == nil && task != heatmapTask {
+		globalLock.Unlock()
+		return // The external engine wakes the scheduler up once it has started
+	}
+	generation := s.generation
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s.cancel = cancel
+	position := g.Position()
+	globalLock.Unlock()
+
+	e := engine.InvalidEdge
+	var values []engine.EdgeValue
+	switch task {
+	case moveTask:
+		e = strategy.BestEdge(ctx, position, settings)
+		if x, ok := strategy.(*engine.ExternalEngine); ok && x.Err() != nil {
+			Message.Send(x.Err().Error())
+		}
+	case hintTask:
+		values = engine.RankEdges(ctx, strategy, position, settings)
+	case heatmapTask:
+		values = engine.EvaluatePosition(ctx, position, settings)
+	case ponderTask:
+		if p, ok := strategy.(engine.Ponderer); ok {
+			p.Ponder(ctx, position, settings)
+		}
+	}
+
+	globalLock.Lock()
+	defer globalLock.Unlock()
+	if generation != s.generation {
+		return // The result is stale
+	}
+	s.cancel = nil
+	switch task {
+	case moveTask:
+		Network.Play(u, e)
+		u.Refresh()
+	case hintTask:
+		s.hint = false
+		u.ShowHint(values)
+		s.signal(HintEvent) // Go on with the next task now that the hint is shown
+	case heatmapTask:
+		s.evaluated = generation
+		u.ShowHeatmap(values)
+		u.Refresh()
+		s.signal(ToggleEvent) // Go on with the next task now that the heatmap is shown
+	}
+}
+
+// IsAIPlayer checks if the player is controlled by the AI of this instance. A remote player never is.
+func IsAIPlayer(t engine.Turn) bool {
+	return ((Chess.AIPlayer1 && t == engine.Player1Turn) || (Chess.AIPlayer2 && t == engine.Player2Turn)) && !IsRemotePlayer(t)
+}
+
+// IsRemotePlayer checks if the player is played from elsewhere: by the other side of a network game, or in a browser.
+func IsRemotePlayer(t engine.Turn) bool { return !Network.Local(t) || API.Seated(t) }
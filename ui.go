@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"image/color"
+	"math"
 	"os"
 	"sync"
 	"time"
@@ -12,6 +13,7 @@ import (
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
+	"github.com/HuXin0817/dots-and-boxes/engine"
 )
 
 var (
@@ -101,18 +103,18 @@ func (g *Theme) GetButtonColor() color.Color {
 	return g.getColorByVariant(LightThemeButtonColor, DarkThemeButtonColor)
 }
 
-// GetPlayerFilledColor returns the color used to fill boxes based on the current player's turn
-func (g *Theme) GetPlayerFilledColor() color.Color {
-	if CurrentTurn == Player1Turn {
+// GetPlayerFilledColor returns the color used to fill boxes based on the given player's turn
+func (g *Theme) GetPlayerFilledColor(t engine.Turn) color.Color {
+	if t == engine.Player1Turn {
 		return Player1FilledColor
 	} else {
 		return Player2FilledColor
 	}
 }
 
-// GetPlayerHighlightColor returns the color used to highlight moves based on the current player's turn
-func (g *Theme) GetPlayerHighlightColor() color.Color {
-	if CurrentTurn == Player1Turn {
+// GetPlayerHighlightColor returns the color used to highlight moves based on the given player's turn
+func (g *Theme) GetPlayerHighlightColor(t engine.Turn) color.Color {
+	if t == engine.Player1Turn {
 		return Player1HighlightColor
 	} else {
 		return Player2HighlightColor
@@ -121,20 +123,40 @@ func (g *Theme) GetPlayerHighlightColor() color.Color {
 
 // UI interface defines the core functions needed to manage the game state.
 type UI interface {
-	Restart(int)            // Restart the game with a new board size
-	Recover([]MoveRecord)   // Recover the game state from a list of move records
-	AddEdge(Edge)           // Add an edge to the board
-	Undo()                  // Undo the last move
-	Refresh()               // Refresh the game state and UI
-	StartAIPlayer1()        // Start AI player 1
-	StartAIPlayer2()        // Start AI player 2
-	SetDotDistance(float32) // Set UI DotDistance
+	Game() *engine.Game             // Game shown by the UI
+	Restart(int)                    // Restart the game with a new board size
+	Recover([]engine.MoveRecord)    // Recover the game state from a list of move records
+	AddEdge(engine.Edge)            // Add an edge to the board
+	Undo()                          // Undo the last move
+	Redo()                          // Redo the last undone move
+	GotoMove([]int)                 // Bring the game to a node of the move tree
+	Tree() *engine.MoveTree         // Move tree of the game
+	Refresh()                       // Refresh the game state and UI
+	StartAIPlayer1()                // Start AI player 1
+	StartAIPlayer2()                // Start AI player 2
+	SetDotDistance(float32)         // Set UI DotDistance
+	ShowHint([]engine.EdgeValue)    // Highlight the edges recommended to the player to move
+	ShowHeatmap([]engine.EdgeValue) // Color every free edge by its evaluation
+	HideHeatmap()                   // Remove the heatmap overlay
 }
 
 // Instantiate the game manager
-var game UI = &ui{}
+var game UI = &ui{g: engine.NewGame(DefaultBoardSize)}
+
+// ui is a view over an engine.Game that draws it with Fyne canvases.
+type ui struct {
+	g         *engine.Game     // Game shown by the UI
+	tree      *engine.MoveTree // Every line played in the game, nil until the first restart
+	panel     *moveTreePanel   // Move tree panel, nil if not shown
+	hintEdges []engine.Edge    // Edges highlighted by the last hint
+	heat      *heatmap         // Heatmap overlay of the position, nil if not shown
+}
+
+// Game returns the game shown by the UI.
+func (ui *ui) Game() *engine.Game { return ui.g }
 
-type ui struct{}
+// Tree returns the move tree of the game.
+func (ui *ui) Tree() *engine.MoveTree { return ui.tree }
 
 // transPosition translates a coordinate to its position on the canvas.
 func (ui *ui) transPosition(x int) float32 {
@@ -142,181 +164,149 @@ func (ui *ui) transPosition(x int) float32 {
 }
 
 // GetDotPosition returns the position of a dot on the canvas.
-func (ui *ui) GetDotPosition(d Dot) (float32, float32) {
-	return ui.transPosition(d.X()), ui.transPosition(d.Y())
+func (ui *ui) GetDotPosition(d engine.Dot) (float32, float32) {
+	return ui.transPosition(ui.g.X(d)), ui.transPosition(ui.g.Y(d))
 }
 
 // getEdgeButtonSizeAndPosition calculates the size and position of the edge button.
-func (ui *ui) getEdgeButtonSizeAndPosition(e Edge) (size fyne.Size, pos fyne.Position) {
-	if e.Dot1().X() == e.Dot2().X() {
+func (ui *ui) getEdgeButtonSizeAndPosition(e engine.Edge) (size fyne.Size, pos fyne.Position) {
+	d1, d2 := ui.g.Dot1(e), ui.g.Dot2(e)
+	if ui.g.X(d1) == ui.g.X(d2) {
 		size = fyne.NewSize(Chess.DotCanvasWidth, Chess.DotCanvasDistance)
-		pos = fyne.NewPos(
-			(ui.transPosition(e.Dot1().X())+ui.transPosition(e.Dot2().X()))/2-size.Width/2+Chess.DotCanvasWidth/2,
-			(ui.transPosition(e.Dot1().Y())+ui.transPosition(e.Dot2().Y()))/2-size.Height/2+Chess.DotCanvasWidth/2,
-		)
 	} else {
 		size = fyne.NewSize(Chess.DotCanvasDistance, Chess.DotCanvasWidth)
-		pos = fyne.NewPos(
-			(ui.transPosition(e.Dot1().X())+ui.transPosition(e.Dot2().X()))/2-size.Width/2+Chess.DotCanvasWidth/2,
-			(ui.transPosition(e.Dot1().Y())+ui.transPosition(e.Dot2().Y()))/2-size.Height/2+Chess.DotCanvasWidth/2,
-		)
 	}
+	pos = fyne.NewPos(
+		(ui.transPosition(ui.g.X(d1))+ui.transPosition(ui.g.X(d2)))/2-size.Width/2+Chess.DotCanvasWidth/2,
+		(ui.transPosition(ui.g.Y(d1))+ui.transPosition(ui.g.Y(d2)))/2-size.Height/2+Chess.DotCanvasWidth/2,
+	)
 	return
 }
 
 // NewDotCanvas creates a new dot canvas for the specified dot.
-func (ui *ui) NewDotCanvas(d Dot) *canvas.Circle {
+func (ui *ui) NewDotCanvas(d engine.Dot) *canvas.Circle {
 	newDotCanvas := canvas.NewCircle(gameTheme.GetDotCanvasColor())
-	newDotCanvas.Resize(fyne.NewSize(Chess.DotCanvasWidth, Chess.DotCanvasWidth))
-	newDotCanvas.Move(fyne.NewPos(ui.GetDotPosition(d)))
+	ui.placeDotCanvas(d, newDotCanvas)
 	return newDotCanvas
 }
 
+// placeDotCanvas sizes and moves the canvas of the dot for the current dot distance.
+func (ui *ui) placeDotCanvas(d engine.Dot, dotCanvas *canvas.Circle) {
+	dotCanvas.Resize(fyne.NewSize(Chess.DotCanvasWidth, Chess.DotCanvasWidth))
+	dotCanvas.Move(fyne.NewPos(ui.GetDotPosition(d)))
+}
+
 // NewEdgeCanvas creates a new edge canvas for the specified edge.
-func (ui *ui) NewEdgeCanvas(e Edge) *canvas.Line {
-	x1 := ui.transPosition(e.Dot1().X()) + Chess.DotCanvasWidth/2
-	y1 := ui.transPosition(e.Dot1().Y()) + Chess.DotCanvasWidth/2
-	x2 := ui.transPosition(e.Dot2().X()) + Chess.DotCanvasWidth/2
-	y2 := ui.transPosition(e.Dot2().Y()) + Chess.DotCanvasWidth/2
+func (ui *ui) NewEdgeCanvas(e engine.Edge) *canvas.Line {
 	newEdgeCanvas := canvas.NewLine(gameTheme.GetDotCanvasColor())
-	newEdgeCanvas.Position1 = fyne.NewPos(x1, y1)
-	newEdgeCanvas.Position2 = fyne.NewPos(x2, y2)
-	newEdgeCanvas.StrokeWidth = Chess.DotCanvasWidth
+	ui.placeEdgeCanvas(e, newEdgeCanvas)
 	return newEdgeCanvas
 }
 
+// placeEdgeCanvas moves the ends of the canvas of the edge for the current dot distance.
+func (ui *ui) placeEdgeCanvas(e engine.Edge, edgeCanvas *canvas.Line) {
+	d1, d2 := ui.g.Dot1(e), ui.g.Dot2(e)
+	x1 := ui.transPosition(ui.g.X(d1)) + Chess.DotCanvasWidth/2
+	y1 := ui.transPosition(ui.g.Y(d1)) + Chess.DotCanvasWidth/2
+	x2 := ui.transPosition(ui.g.X(d2)) + Chess.DotCanvasWidth/2
+	y2 := ui.transPosition(ui.g.Y(d2)) + Chess.DotCanvasWidth/2
+	edgeCanvas.Position1 = fyne.NewPos(x1, y1)
+	edgeCanvas.Position2 = fyne.NewPos(x2, y2)
+	edgeCanvas.StrokeWidth = Chess.DotCanvasWidth
+}
+
 // NewBoxCanvas creates a new box canvas for the specified box.
-func (ui *ui) NewBoxCanvas(box Box) *canvas.Rectangle {
-	d := Dot(box)
-	x := ui.transPosition(d.X()) + Chess.DotCanvasWidth
-	y := ui.transPosition(d.Y()) + Chess.DotCanvasWidth
+func (ui *ui) NewBoxCanvas(box engine.Box) *canvas.Rectangle {
 	newBoxCanvas := canvas.NewRectangle(gameTheme.GetThemeColor())
-	newBoxCanvas.Move(fyne.NewPos(x, y))
-	newBoxCanvas.Resize(fyne.NewSize(Chess.BoxCanvasSize, Chess.BoxCanvasSize))
+	ui.placeBoxCanvas(box, newBoxCanvas)
 	return newBoxCanvas
 }
 
+// placeBoxCanvas sizes and moves the canvas of the box for the current dot distance.
+func (ui *ui) placeBoxCanvas(box engine.Box, boxCanvas *canvas.Rectangle) {
+	d := engine.Dot(box)
+	x := ui.transPosition(ui.g.X(d)) + Chess.DotCanvasWidth
+	y := ui.transPosition(ui.g.Y(d)) + Chess.DotCanvasWidth
+	boxCanvas.Move(fyne.NewPos(x, y))
+	boxCanvas.Resize(fyne.NewSize(Chess.BoxCanvasSize, Chess.BoxCanvasSize))
+}
+
+// layout places every canvas and edge button of the board for the current dot distance, keeping their colors.
+func (ui *ui) layout() {
+	for d, dotCanvas := range DotCanvases {
+		ui.placeDotCanvas(d, dotCanvas)
+	}
+	for e, edgeCanvas := range EdgesCanvases {
+		ui.placeEdgeCanvas(e, edgeCanvas)
+	}
+	boxesCanvasLock.Lock()
+	for box, boxCanvas := range BoxesCanvases {
+		ui.placeBoxCanvas(box, boxCanvas)
+	}
+	boxesCanvasLock.Unlock()
+	for e, button := range EdgeButtons {
+		size, pos := ui.getEdgeButtonSizeAndPosition(e)
+		button.Resize(size)
+		button.Move(pos)
+	}
+}
+
 // Refresh updates the UI and saves the game state to a file.
 func (ui *ui) Refresh() {
 	RefreshMenu()
 	Container.Refresh()
+	Chess.ChessMoveRecords = ui.g.Records()
+	Chess.MoveTree = ui.tree
+	Network.Sync(ui.g)
+	if ui.panel != nil {
+		ui.panel.Refresh(ui.tree)
+	}
 	if err := Chess.Refresh(); err != nil {
 		Message.Send(err.Error())
 	}
 }
 
-// notifySignChan sends a signal to the AI player's channel if it's their turn.
-func (ui *ui) notifySignChan() {
-	if (Chess.AIPlayer1 && CurrentTurn == Player1Turn) || (Chess.AIPlayer2 && CurrentTurn == Player2Turn) {
-		select {
-		case SignChan <- struct{}{}:
-		default:
-		}
-	}
-}
+// isAITurn checks if the player to move is controlled by the AI.
+func (ui *ui) isAITurn() bool { return IsAIPlayer(ui.g.Turn()) }
 
 // restart initializes a new game with the specified board size.
 func (ui *ui) restart(NewBoardSize int) {
 	Chess.BoardSize = NewBoardSize
-	Chess.BoardSizePower = Dot(Chess.BoardSize * Chess.BoardSize)
 	Chess.MainWindowSize = Chess.DotCanvasDistance*float32(Chess.BoardSize) + Chess.BoardMargin - 5
-	MainWindow.Resize(fyne.NewSize(Chess.MainWindowSize, Chess.MainWindowSize))
-
-	// Initialize dots
-	AllDots = []Dot{}
-	for i := 0; i < Chess.BoardSize; i++ {
-		for j := 0; j < Chess.BoardSize; j++ {
-			AllDots = append(AllDots, NewDot(i, j))
-		}
-	}
-
-	// Initialize edges
-	AllEdges = make(map[Edge]struct{})
-	for i := 0; i < Chess.BoardSize; i++ {
-		for j := 0; j < Chess.BoardSize; j++ {
-			d := NewDot(i, j)
-			if i+1 < Chess.BoardSize {
-				AllEdges[NewEdge(d, NewDot(i+1, j))] = struct{}{}
-			}
-			if j+1 < Chess.BoardSize {
-				AllEdges[NewEdge(d, NewDot(i, j+1))] = struct{}{}
-			}
-		}
-	}
-	AllEdgesCount = len(AllEdges)
-
-	// Initialize boxes
-	AllBoxes = []Box{}
-	for _, d := range AllDots {
-		if d.X() < Chess.BoardSize-1 && d.Y() < Chess.BoardSize-1 {
-			AllBoxes = append(AllBoxes, Box(d))
-		}
-	}
-
-	// Initialize edge-adjacent boxes
-	EdgeAdjacentBoxes = make(map[Edge][]Box)
-	for e := range AllEdges {
-		x := e.Dot2().X() - 1
-		y := e.Dot2().Y() - 1
-		if x >= 0 && y >= 0 {
-			EdgeAdjacentBoxes[e] = []Box{Box(e.Dot1()), Box(NewDot(x, y))}
-			continue
-		}
-		EdgeAdjacentBoxes[e] = []Box{Box(e.Dot1())}
-	}
-
-	// Initialize all edges in each box
-	AllEdgesInBox = make(map[Box][]Edge)
-	for _, b := range AllBoxes {
-		x := Dot(b).X()
-		y := Dot(b).Y()
-		D00 := NewDot(x, y)
-		D10 := NewDot(x+1, y)
-		D01 := NewDot(x, y+1)
-		D11 := NewDot(x+1, y+1)
-		edges := []Edge{
-			NewEdge(D00, D01),
-			NewEdge(D00, D10),
-			NewEdge(D01, D11),
-			NewEdge(D10, D11),
-		}
-		AllEdgesInBox[b] = edges
-	}
+	ui.g = engine.NewGame(NewBoardSize)
+	ui.hintEdges = nil
+	ui.heat = nil
+	Scheduler.Notify(RestartEvent)
 
 	// Initialize canvases
-	DotCanvases = make(map[Dot]*canvas.Circle)
-	EdgesCanvases = make(map[Edge]*canvas.Line)
+	DotCanvases = make(map[engine.Dot]*canvas.Circle)
+	EdgesCanvases = make(map[engine.Edge]*canvas.Line)
 	boxesCanvasLock.Lock()
-	BoxesCanvases = make(map[Box]*canvas.Rectangle)
+	BoxesCanvases = make(map[engine.Box]*canvas.Rectangle)
 	boxesCanvasLock.Unlock()
-	EdgeButtons = make(map[Edge]*widget.Button)
-	BoxesFilledColor = make(map[Box]color.Color)
+	EdgeButtons = make(map[engine.Edge]*widget.Button)
+	BoxesFilledColor = make(map[engine.Box]color.Color)
 	Container = container.NewWithoutLayout()
-	Chess.ChessMoveRecords = []MoveRecord{}
-	CurrentTurn = Player1Turn
-	Player1Score = 0
-	Player2Score = 0
-	CurrentBoard = NewBoard()
 
 	// Add boxes to the container
 	boxesCanvasLock.Lock()
-	for _, b := range AllBoxes {
+	for _, b := range ui.g.Boxes {
 		BoxesCanvases[b] = ui.NewBoxCanvas(b)
 		Container.Add(BoxesCanvases[b])
 	}
 	boxesCanvasLock.Unlock()
 
 	// Add edges to the container
-	for e := range AllEdges {
+	for _, e := range ui.g.Edges {
 		EdgesCanvases[e] = ui.NewEdgeCanvas(e)
 		Container.Add(EdgesCanvases[e])
 		EdgeButtons[e] = widget.NewButton("", func() {
 			globalLock.Lock()
 			defer globalLock.Unlock()
-			if (Chess.AIPlayer1 && CurrentTurn == Player1Turn) || (Chess.AIPlayer2 && CurrentTurn == Player2Turn) {
+			if ui.isAITurn() || IsRemotePlayer(ui.g.Turn()) {
 				return
 			}
-			ui.AddEdge(e)
+			Network.Play(ui, e)
 			ui.Refresh()
 		})
 		size, pos := ui.getEdgeButtonSizeAndPosition(e)
@@ -326,43 +316,59 @@ func (ui *ui) restart(NewBoardSize int) {
 	}
 
 	// Add dots to the container
-	for _, d := range AllDots {
+	for _, d := range ui.g.Dots {
 		DotCanvases[d] = ui.NewDotCanvas(d)
 		Container.Add(DotCanvases[d])
 	}
+	ui.setContent()
+}
 
-	// Start a goroutine to handle AI moves
-	go func() {
-		ui.notifySignChan()
-		for range SignChan {
-			globalLock.Lock()
-			ui.AddEdge(GetBestEdge())
-			ui.Refresh()
-			globalLock.Unlock()
-		}
-	}()
-	MainWindow.SetContent(Container)
+// setContent shows the board in the main window, with the move tree panel on its right if it is enabled.
+func (ui *ui) setContent() {
+	size := fyne.NewSize(Chess.MainWindowSize, Chess.MainWindowSize)
+	if !Chess.MoveTreePanel {
+		ui.panel = nil
+		MainWindow.SetContent(Container)
+		MainWindow.Resize(size)
+		return
+	}
+	if ui.panel == nil {
+		ui.panel = newMoveTreePanel(ui.tree)
+	}
+	panel := container.NewGridWrap(fyne.NewSize(MoveTreePanelWidth, Chess.MainWindowSize), ui.panel.widget)
+	MainWindow.SetContent(container.NewBorder(nil, nil, nil, panel, Container))
+	MainWindow.Resize(size.AddWidthHeight(MoveTreePanelWidth, 0))
 }
 
 // Restart restarts the game with the given board size and sends a message.
 func (ui *ui) Restart(size int) {
+	ui.tree = engine.NewMoveTree(size)
 	ui.restart(size)
+	API.Publish("restart", nil)
 	Message.Send("Game Start! BoardSize: %v", Chess.BoardSize)
 }
 
-// storeMoveRecord saves the current game state to a log file.
+// recordString returns the string representation of the move record.
+func (ui *ui) recordString(m engine.MoveRecord) string {
+	return fmt.Sprintf("%v Step: %v, Turn: %v, Edge: %v, Player1Score: %v, Player2Score: %v", m.TimeStamp.Format(time.DateTime), m.Step, m.Player, ui.g.EdgeString(m.MoveEdge), m.Player1Score, m.Player2Score)
+}
+
+// storeMoveRecord saves the current game state to a text log file and to a structured one.
 func (ui *ui) storeMoveRecord(WinMessage string) {
-	startTimeStamp := Chess.ChessMoveRecords[0].TimeStamp.Format(time.DateTime)
-	endTimeStamp := Chess.ChessMoveRecords[len(Chess.ChessMoveRecords)-1].TimeStamp.Format(time.DateTime)
-	gameName := fmt.Sprintf("Game %v", startTimeStamp)
-	f, err := os.Create(gameName + ".log")
+	records := ui.g.Records()
+	startTimeStamp := records[0].TimeStamp.Format(time.DateTime)
+	endTimeStamp := records[len(records)-1].TimeStamp.Format(time.DateTime)
+	if err := saveGame(GameName(records)+GameFileSuffix, engine.NewGameLog(ui.g, WinMessage)); err != nil {
+		Message.Send(err.Error())
+	}
+	f, err := os.Create(GameName(records) + GameLogFileSuffix)
 	if err != nil {
 		Message.Send(err.Error())
 		return
 	}
-	record := fmt.Sprintf("%v BoardSize: %v\n", startTimeStamp, Chess.BoardSize)
-	for _, r := range Chess.ChessMoveRecords {
-		record = record + r.String() + "\n"
+	record := fmt.Sprintf("%v BoardSize: %v\n", startTimeStamp, ui.g.BoardSize)
+	for _, r := range records {
+		record = record + ui.recordString(r) + "\n"
 	}
 	record += endTimeStamp + " " + WinMessage
 	if _, err := f.WriteString(record); err != nil {
@@ -376,7 +382,7 @@ func (ui *ui) startTipAnimation(nowStep int, boxesCanvas *canvas.Rectangle) {
 	var animation *fyne.Animation
 	currentThemeVariant := CurrentThemeVariant
 	animation = canvas.NewColorRGBAAnimation(TipColor, gameTheme.GetThemeColor(), time.Second, func(c color.Color) {
-		if nowStep != CurrentBoard.Size() {
+		if nowStep != ui.g.Step() {
 			animation.Stop()
 			return
 		}
@@ -393,27 +399,65 @@ func (ui *ui) startTipAnimation(nowStep int, boxesCanvas *canvas.Rectangle) {
 	fyne.CurrentApp().Driver().StartAnimation(animation)
 }
 
-// AddEdge adds an edge to the board and updates the game state.
-func (ui *ui) AddEdge(e Edge) {
-	if Chess.BoardSize <= 1 {
-		return
+// startTipAnimations highlights every box that has three of its sides drawn.
+func (ui *ui) startTipAnimations() {
+	nowStep := ui.g.Step()
+	for _, box := range ui.g.Boxes {
+		if ui.g.EdgesCountInBox(ui.g.Board(), box) == 3 {
+			boxesCanvasLock.Lock()
+			boxesCanvas := BoxesCanvases[box]
+			originalColor := BoxesFilledColor[box]
+			boxesCanvasLock.Unlock()
+			go func() {
+				ui.startTipAnimation(nowStep, boxesCanvas)
+				boxesCanvasLock.Lock()
+				boxesCanvas.FillColor = originalColor
+				boxesCanvasLock.Unlock()
+			}()
+		}
 	}
-	if CurrentBoard.Contains(e) {
-		return
+}
+
+// paintEdge draws the edge and the boxes it completed in the colors of the player who moved.
+func (ui *ui) paintEdge(e engine.Edge, player engine.Turn, obtainsBoxes []engine.Box) {
+	EdgeButtons[e].Hide()
+	boxesCanvasLock.Lock()
+	for _, box := range obtainsBoxes {
+		playerFilledColor := gameTheme.GetPlayerFilledColor(player)
+		BoxesCanvases[box].FillColor = playerFilledColor
+		BoxesFilledColor[box] = playerFilledColor
 	}
-	if e == InvalidEdge {
+	boxesCanvasLock.Unlock()
+	EdgesCanvases[e].StrokeColor = gameTheme.GetPlayerHighlightColor(player)
+}
+
+// unpaintEdge erases the edge of the delta and clears the boxes around it: the boxes it completed, and the boxes
+// left highlighted by a tip animation, which startTipAnimations highlights again if they still have three sides.
+func (ui *ui) unpaintEdge(d engine.Delta) {
+	EdgeButtons[d.Edge].Show()
+	EdgesCanvases[d.Edge].StrokeColor = gameTheme.GetDotCanvasColor()
+	EdgesCanvases[d.Edge].Refresh()
+	boxesCanvasLock.Lock()
+	for _, box := range ui.g.AdjacentBoxes(d.Edge) {
+		delete(BoxesFilledColor, box)
+		BoxesCanvases[box].FillColor = gameTheme.GetThemeColor()
+		BoxesCanvases[box].Refresh()
+	}
+	boxesCanvasLock.Unlock()
+}
+
+// AddEdge adds an edge to the board and updates the game state.
+func (ui *ui) AddEdge(e engine.Edge) {
+	player := ui.g.Turn()
+	obtainsBoxes, err := ui.g.Play(e)
+	if err != nil {
 		return
 	}
-	Chess.ChessMoveRecords = append(Chess.ChessMoveRecords, MoveRecord{
-		TimeStamp:    time.Now(),
-		Step:         CurrentBoard.Size(),
-		Player:       CurrentTurn,
-		MoveEdge:     e,
-		Player1Score: Player1Score,
-		Player2Score: Player2Score,
-	})
-	nowStep := CurrentBoard.Size()
-	obtainsBoxes := ObtainsBoxes(CurrentBoard, e)
+	records := ui.g.Records()
+	ui.tree.Play(records[len(records)-1])
+	Scheduler.Notify(MoveEvent)
+	ui.clearHint()
+	ui.HideHeatmap()
 	score := len(obtainsBoxes)
 	if Chess.OpenMusic {
 		var wg sync.WaitGroup
@@ -432,102 +476,157 @@ func (ui *ui) AddEdge(e Edge) {
 			}
 		}()
 	}
-	EdgeButtons[e].Hide()
-	boxesCanvasLock.Lock()
-	for _, box := range obtainsBoxes {
-		playerFilledColor := gameTheme.GetPlayerFilledColor()
-		BoxesCanvases[box].FillColor = playerFilledColor
-		BoxesFilledColor[box] = playerFilledColor
-	}
-	boxesCanvasLock.Unlock()
-	EdgesCanvases[e].StrokeColor = gameTheme.GetPlayerHighlightColor()
-	if CurrentTurn == Player1Turn {
-		Player1Score += score
-	} else {
-		Player2Score += score
-	}
-	if score == 0 {
-		ChangeTurn(&CurrentTurn)
-	}
-	CurrentBoard.Add(e)
-	nowStep++
-	for _, box := range AllBoxes {
-		if EdgesCountInBox(CurrentBoard, box) == 3 {
-			boxesCanvasLock.Lock()
-			boxesCanvas := BoxesCanvases[box]
-			originalColor := BoxesFilledColor[box]
-			boxesCanvasLock.Unlock()
-			go func() {
-				ui.startTipAnimation(nowStep, boxesCanvas)
-				boxesCanvasLock.Lock()
-				boxesCanvas.FillColor = originalColor
-				boxesCanvasLock.Unlock()
-			}()
-		}
-	}
-	if nowStep == AllEdgesCount {
-		var WinMessage string
-		if Player1Score > Player2Score {
-			WinMessage = "Player1 Win!"
-		} else if Player1Score < Player2Score {
-			WinMessage = "Player2 Win!"
-		} else if Player1Score == Player2Score {
-			WinMessage = "Draw!"
-		}
+	ui.paintEdge(e, player, obtainsBoxes)
+	ui.startTipAnimations()
+	API.Publish("move", &records[len(records)-1])
+	if ui.g.Over() {
+		WinMessage := winMessage(ui.g)
 		Message.Send(WinMessage)
 		ui.storeMoveRecord(WinMessage)
-		if Chess.AutoRestartGame {
+		if Chess.AutoRestartGame && !Network.Joined() { // The host restarts a network game
 			go func() {
 				time.Sleep(2 * time.Second)
+				globalLock.Lock()
+				defer globalLock.Unlock()
+				defer ui.Refresh()
 				ui.Restart(Chess.BoardSize)
 			}()
 		}
 	}
-	ui.notifySignChan()
 }
 
-// Undo reverts the last move.
+// winMessage returns the result of the finished game.
+func winMessage(g *engine.Game) string {
+	switch g.Winner() {
+	case engine.Player1Turn:
+		return "Player1 Win!"
+	case engine.Player2Turn:
+		return "Player2 Win!"
+	default:
+		return "Draw!"
+	}
+}
+
+// Undo reverts the last move, updating only the canvases of its edge and of the boxes around it.
 func (ui *ui) Undo() {
-	moveRecord := append([]MoveRecord{}, Chess.ChessMoveRecords...)
-	if len(moveRecord) > 0 {
-		r := moveRecord[len(moveRecord)-1]
-		Message.Send("Undo Edge %v", r.MoveEdge)
-		moveRecord = moveRecord[:len(moveRecord)-1]
-		ui.Recover(moveRecord)
+	records := ui.g.Records()
+	d, ok := ui.g.Undo()
+	if !ok {
+		return
 	}
+	ui.tree.Undo()
+	Scheduler.Notify(UndoEvent)
+	ui.clearHint()
+	ui.HideHeatmap()
+	ui.unpaintEdge(d)
+	ui.startTipAnimations()
+	API.Publish("undo", &records[len(records)-1])
+	Message.Send("Undo Edge %v", ui.g.EdgeString(d.Edge))
 }
 
-// Recover replays the move records to restore the game state.
-func (ui *ui) Recover(MoveRecord []MoveRecord) {
-	if Chess.OpenMusic {
-		Chess.OpenMusic = !Chess.OpenMusic
-		defer func() { Chess.OpenMusic = !Chess.OpenMusic }()
+// Redo replays the move undone last, or the move last visited from the current node of the move tree.
+func (ui *ui) Redo() {
+	if r, ok := ui.tree.Next(); ok {
+		Message.Send("Redo Edge %v", ui.g.EdgeString(r.MoveEdge))
+		ui.AddEdge(r.MoveEdge)
+	}
+}
+
+// GotoMove brings the game to the node of the move tree reached by the path.
+func (ui *ui) GotoMove(path []int) {
+	ui.Recover(ui.tree.Records(path))
+}
+
+// Recover replays the move records to restore the game state, and moves to them in the move tree,
+// adding them as a new variation if needed.
+func (ui *ui) Recover(MoveRecord []engine.MoveRecord) {
+	if ui.tree == nil && Chess.MoveTree != nil && Chess.MoveTree.Root != nil {
+		ui.tree = Chess.MoveTree // Restored from the meta file
+	}
+	if ui.tree == nil || ui.tree.BoardSize != Chess.BoardSize {
+		ui.tree = engine.NewMoveTree(Chess.BoardSize)
 	}
 	ui.restart(Chess.BoardSize)
-	for _, r := range MoveRecord {
-		ui.AddEdge(r.MoveEdge)
+	if err := ui.g.Replay(MoveRecord); err != nil {
+		Message.Send(err.Error())
+	}
+	ui.tree.Goto(ui.g.Records())
+	for _, r := range ui.g.Records() {
+		ui.paintEdge(r.MoveEdge, r.Player, nil)
 	}
-	Chess.ChessMoveRecords = MoveRecord
+	boxesCanvasLock.Lock()
+	for _, box := range ui.g.Boxes {
+		if player := ui.g.BoxOwner(box); player != 0 {
+			playerFilledColor := gameTheme.GetPlayerFilledColor(player)
+			BoxesCanvases[box].FillColor = playerFilledColor
+			BoxesFilledColor[box] = playerFilledColor
+		}
+	}
+	boxesCanvasLock.Unlock()
+	ui.startTipAnimations()
+	API.Publish("restart", nil)
 }
 
 // StartAIPlayer1 starts or stops AI player 1.
 func (ui *ui) StartAIPlayer1() {
-	if !Chess.AIPlayer1 {
-		ui.notifySignChan()
-	}
 	message := GetMessage("AIPlayer1", !Chess.AIPlayer1)
 	Message.Send(message)
 	Chess.AIPlayer1 = !Chess.AIPlayer1
+	Scheduler.Notify(ToggleEvent)
 }
 
 // StartAIPlayer2 starts or stops AI player 2.
 func (ui *ui) StartAIPlayer2() {
-	if !Chess.AIPlayer2 {
-		ui.notifySignChan()
-	}
 	message := GetMessage("AIPlayer2", !Chess.AIPlayer2)
 	Message.Send(message)
 	Chess.AIPlayer2 = !Chess.AIPlayer2
+	Scheduler.Notify(ToggleEvent)
+}
+
+// ShowHint highlights the recommended edge and the best alternatives, and sends their expected margins.
+// It changes neither the turn nor the move records.
+func (ui *ui) ShowHint(values []engine.EdgeValue) {
+	ui.clearHint()
+	if len(values) == 0 {
+		Message.Send("No Hint Available")
+		return
+	}
+	values = values[:min(len(values), HintAlternatives+1)]
+	message := fmt.Sprintf("Hint for %v:", ui.g.Turn())
+	for i, v := range values {
+		importance := widget.WarningImportance
+		if i == 0 {
+			importance = widget.HighImportance
+		}
+		EdgeButtons[v.Edge].Importance = importance
+		EdgeButtons[v.Edge].Refresh()
+		ui.hintEdges = append(ui.hintEdges, v.Edge)
+		message += fmt.Sprintf("\n%v Margin: %v", ui.g.EdgeString(v.Edge), marginString(v))
+	}
+	Message.Send(message)
+}
+
+// clearHint removes the highlight of the edges of the last hint.
+func (ui *ui) clearHint() {
+	for _, e := range ui.hintEdges {
+		if button, ok := EdgeButtons[e]; ok {
+			button.Importance = widget.MediumImportance
+			button.Refresh()
+		}
+	}
+	ui.hintEdges = nil
+}
+
+// marginString formats the expected margin of an edge value.
+func marginString(v engine.EdgeValue) string {
+	switch {
+	case math.IsNaN(v.Margin):
+		return "?"
+	case v.Exact:
+		return fmt.Sprintf("%+.0f (exact)", v.Margin)
+	default:
+		return fmt.Sprintf("%+.1f", v.Margin)
+	}
 }
 
 // SetDotDistance sets the distance between dots and updates the board layout.
@@ -537,7 +636,13 @@ func (ui *ui) SetDotDistance(d float32) {
 	Chess.BoardMargin = Chess.DotCanvasDistance / 3 * 2
 	Chess.BoxCanvasSize = Chess.DotCanvasDistance - Chess.DotCanvasWidth
 	Chess.MainWindowSize = Chess.DotCanvasDistance*float32(Chess.BoardSize) + Chess.BoardMargin - 5
-	MainWindow.Resize(fyne.NewSize(Chess.MainWindowSize, Chess.MainWindowSize))
-	moveRecord := append([]MoveRecord{}, Chess.ChessMoveRecords...)
-	game.Recover(moveRecord)
+	if Container == nil {
+		return // The board is laid out when the game starts
+	}
+	ui.layout()
+	if ui.heat != nil {
+		ui.HideHeatmap()
+		Scheduler.Notify(ToggleEvent) // Evaluate the heatmap again to lay it out
+	}
+	ui.setContent()
 }
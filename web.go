@@ -0,0 +1,136 @@
+package main
+
+import (
+	"crypto/rand"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/HuXin0817/dots-and-boxes/engine"
+	"github.com/gin-gonic/gin"
+)
+
+// The browser client is a board served by the HTTP API at its root, so that browsers on the same network can watch the
+// game or play a seat. POST /api/seat/1 or /api/seat/2 seats a browser as a player and returns the token of the seat.
+// The moves of the seat are posted to /api/move with the token in the SeatTokenHeader header, and are checked like
+// the moves drawn on the board of this instance. DELETE /api/seat/1 or /api/seat/2 with the token leaves the seat,
+// which is also left when the WebSocket opened with ?token=<token> closes.
+
+// SeatTokenHeader is the header carrying the token of the seat of a browser.
+const SeatTokenHeader = "X-Seat-Token"
+
+//go:embed web
+var webAssets embed.FS
+
+// registerWeb adds the browser client and its seats to the routes of the HTTP API.
+func (a *apiServer) registerWeb(r *gin.Engine) {
+	r.GET("/", func(c *gin.Context) {
+		page, err := webAssets.ReadFile("web/index.html")
+		if err != nil {
+			c.String(http.StatusInternalServerError, err.Error())
+			return
+		}
+		c.Data(http.StatusOK, "text/html; charset=utf-8", page)
+	})
+	r.POST("/api/seat/:player", func(c *gin.Context) {
+		token, err := a.takeSeat(c.Param("player"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"token": token})
+	})
+	r.DELETE("/api/seat/:player", func(c *gin.Context) {
+		apiReply(c, a.leaveSeat(c.Param("player"), c.GetHeader(SeatTokenHeader)))
+	})
+}
+
+// Seated checks if the player is played in a browser. It must be called with globalLock held.
+func (a *apiServer) Seated(t engine.Turn) bool {
+	if a == nil {
+		return false
+	}
+	_, ok := a.seats[t]
+	return ok
+}
+
+// takeSeat seats a browser as the player, which must be free and played on this instance, and returns its token.
+func (a *apiServer) takeSeat(player string) (string, error) {
+	t, err := parsePlayer(player)
+	if err != nil {
+		return "", err
+	}
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	globalLock.Lock()
+	defer globalLock.Unlock()
+	defer game.Refresh()
+	if API != a {
+		return "", errors.New("the HTTP API is stopped")
+	}
+	if a.Seated(t) {
+		return "", fmt.Errorf("%v is already played in a browser", t)
+	}
+	if !Network.Local(t) {
+		return "", fmt.Errorf("%v is played by the other side of the network game", t)
+	}
+	token := hex.EncodeToString(b)
+	a.seats[t] = token
+	Scheduler.Notify(ToggleEvent) // The AI player, if any, gives the seat up
+	Message.Send("%v Is Played in a Browser", t)
+	a.Publish("seat", nil)
+	return token, nil
+}
+
+// leaveSeat frees the player seated with the token.
+func (a *apiServer) leaveSeat(player, token string) error {
+	t, err := parsePlayer(player)
+	if err != nil {
+		return err
+	}
+	globalLock.Lock()
+	defer globalLock.Unlock()
+	defer game.Refresh()
+	if token == "" || a.seats[t] != token {
+		return fmt.Errorf("%v is not played with this token", t)
+	}
+	a.leaveSeats(token)
+	return nil
+}
+
+// leaveSeats frees every player seated with the token. It must be called with globalLock held.
+func (a *apiServer) leaveSeats(token string) {
+	for t, seatToken := range a.seats {
+		if seatToken == token {
+			delete(a.seats, t)
+			Scheduler.Notify(ToggleEvent) // The AI player, if any, takes the seat back
+			Message.Send("%v Left the Browser", t)
+			a.Publish("seat", nil)
+		}
+	}
+}
+
+// seatMove draws the edge for the player seated with the token. Like a click on the board of this instance, the move
+// is refused unless it is the turn of that player and the edge is free.
+func (a *apiServer) seatMove(token, move string) error {
+	globalLock.Lock()
+	defer globalLock.Unlock()
+	defer game.Refresh()
+	g := game.Game()
+	if token != a.seats[g.Turn()] {
+		return fmt.Errorf("it is the turn of %v", g.Turn())
+	}
+	e, err := g.ParseEdge(move)
+	if err != nil {
+		return err
+	}
+	if err := g.Validate(e); err != nil {
+		return err
+	}
+	Network.Play(game, e)
+	return nil
+}